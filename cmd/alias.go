@@ -8,6 +8,8 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var aliasSession bool
+
 // aliasCmd represents the alias command
 var aliasCmd = &cobra.Command{
 	Use:   "alias",
@@ -23,7 +25,11 @@ To use them, add to your shell config:
   source ~/.config/wrapper/aliases.zsh
 
   # For fish (~/.config/fish/config.fish)
-  source ~/.config/wrapper/aliases.fish`,
+  source ~/.config/wrapper/aliases.fish
+
+With --session, '<binary> profile set' and '<binary> profile unset' only
+affect the shell they are run in: the selection is stored in the shell's
+environment and current.env is used as a fallback for other shells.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		homeDir, err := os.UserHomeDir()
 		if err != nil {
@@ -59,15 +65,15 @@ To use them, add to your shell config:
 		}
 
 		// Generate alias files
-		if err := generateBashAliases(configDir, binaries); err != nil {
+		if err := generateBashAliases(configDir, binaries, aliasSession); err != nil {
 			return err
 		}
 
-		if err := generateZshAliases(configDir, binaries); err != nil {
+		if err := generateZshAliases(configDir, binaries, aliasSession); err != nil {
 			return err
 		}
 
-		if err := generateFishAliases(configDir, binaries); err != nil {
+		if err := generateFishAliases(configDir, binaries, aliasSession); err != nil {
 			return err
 		}
 
@@ -90,7 +96,7 @@ To use them, add to your shell config:
 	},
 }
 
-func generateBashAliases(configDir string, binaries []string) error {
+func generateBashAliases(configDir string, binaries []string, session bool) error {
 	filePath := filepath.Join(configDir, "aliases.bash")
 	file, err := os.Create(filePath)
 	if err != nil {
@@ -104,13 +110,17 @@ func generateBashAliases(configDir string, binaries []string) error {
 	fmt.Fprintln(file)
 
 	for _, binary := range binaries {
+		if session {
+			writePosixSessionFunction(file, binary, "bash")
+			continue
+		}
 		fmt.Fprintf(file, "%s() { wrapper %s \"$@\"; }\n", binary, binary)
 	}
 
 	return nil
 }
 
-func generateZshAliases(configDir string, binaries []string) error {
+func generateZshAliases(configDir string, binaries []string, session bool) error {
 	filePath := filepath.Join(configDir, "aliases.zsh")
 	file, err := os.Create(filePath)
 	if err != nil {
@@ -124,13 +134,17 @@ func generateZshAliases(configDir string, binaries []string) error {
 	fmt.Fprintln(file)
 
 	for _, binary := range binaries {
+		if session {
+			writePosixSessionFunction(file, binary, "zsh")
+			continue
+		}
 		fmt.Fprintf(file, "%s() { wrapper %s \"$@\"; }\n", binary, binary)
 	}
 
 	return nil
 }
 
-func generateFishAliases(configDir string, binaries []string) error {
+func generateFishAliases(configDir string, binaries []string, session bool) error {
 	filePath := filepath.Join(configDir, "aliases.fish")
 	file, err := os.Create(filePath)
 	if err != nil {
@@ -144,12 +158,38 @@ func generateFishAliases(configDir string, binaries []string) error {
 	fmt.Fprintln(file)
 
 	for _, binary := range binaries {
+		if session {
+			fmt.Fprintf(file, `function %[1]s
+    if test (count $argv) -ge 2; and test "$argv[1]" = profile; and contains -- "$argv[2]" set unset
+        set -l __wrapper_out (wrapper %[1]s profile $argv[2] --shell fish $argv[3..-1]); and eval "$__wrapper_out"
+    else
+        wrapper %[1]s $argv
+    end
+end
+`, binary)
+			continue
+		}
 		fmt.Fprintf(file, "function %s; wrapper %s $argv; end\n", binary, binary)
 	}
 
 	return nil
 }
 
+// writePosixSessionFunction writes a bash/zsh function evaluating the shell
+// code printed by 'profile set --shell' and 'profile unset --shell'
+func writePosixSessionFunction(file *os.File, binary, shell string) {
+	fmt.Fprintf(file, `%[1]s() {
+    if [ "$1" = "profile" ] && { [ "$2" = "set" ] || [ "$2" = "unset" ]; }; then
+        local __wrapper_out
+        __wrapper_out="$(wrapper %[1]s profile "$2" --shell %[2]s "${@:3}")" && eval "$__wrapper_out"
+    else
+        wrapper %[1]s "$@"
+    fi
+}
+`, binary, shell)
+}
+
 func init() {
+	aliasCmd.Flags().BoolVar(&aliasSession, "session", false, "Generate functions selecting profiles per shell session")
 	rootCmd.AddCommand(aliasCmd)
 }
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/jycamier/wrapper/internal/domain"
+	"github.com/spf13/cobra"
+)
+
+var allowForce bool
+
+// allowCmd represents the allow command
+var allowCmd = &cobra.Command{
+	Use:   "allow [path]",
+	Short: "Trust a .wrapper file",
+	Long: `Trust the .wrapper file nearest to the current directory (or to path).
+Binaries executed below that directory then use the profiles it pins, which
+are shown before asking for confirmation.
+A trusted file must be allowed again after each modification.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		finder, err := setupProjectConfigFinder()
+		if err != nil {
+			return err
+		}
+
+		config, err := findProjectConfig(finder, args)
+		if err != nil {
+			return err
+		}
+
+		// Show what the file pins before trusting it
+		profiles := config.Profiles()
+		binaries := make([]string, 0, len(profiles))
+		for binary := range profiles {
+			binaries = append(binaries, binary)
+		}
+		sort.Strings(binaries)
+
+		if len(binaries) == 0 {
+			fmt.Printf("%s pins no profile\n", config.Path())
+		} else {
+			fmt.Printf("%s pins:\n", config.Path())
+		}
+		for _, binary := range binaries {
+			fmt.Printf("  %s → %s\n", binary, profiles[binary])
+		}
+
+		if !allowForce && !confirm("Trust it?") {
+			fmt.Println("Aborted")
+			return nil
+		}
+
+		if err := finder.Allow(config.Path()); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Trusted %s\n", config.Path())
+
+		return nil
+	},
+}
+
+// denyCmd represents the deny command
+var denyCmd = &cobra.Command{
+	Use:   "deny [path]",
+	Short: "Revoke the trust of a .wrapper file",
+	Long:  `Revoke the trust of the .wrapper file nearest to the current directory (or to path)`,
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		finder, err := setupProjectConfigFinder()
+		if err != nil {
+			return err
+		}
+
+		config, err := findProjectConfig(finder, args)
+		if err != nil {
+			return err
+		}
+
+		if err := finder.Deny(config.Path()); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Revoked trust of %s\n", config.Path())
+
+		return nil
+	},
+}
+
+// findProjectConfig locates the .wrapper file designated by the optional path
+// argument: the file itself, or the nearest file from a directory
+func findProjectConfig(finder domain.ProjectConfigFinder, args []string) (*domain.ProjectConfig, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	if len(args) == 1 {
+		info, err := os.Stat(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", args[0], err)
+		}
+
+		if !info.IsDir() {
+			return finder.Load(args[0])
+		}
+		dir = args[0]
+	}
+
+	config, err := finder.Find(dir)
+	if err != nil {
+		if err == domain.ErrProjectConfigNotFound {
+			return nil, fmt.Errorf("no .wrapper file found in %s or its parents", dir)
+		}
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func init() {
+	allowCmd.Flags().BoolVarP(&allowForce, "force", "f", false, "Trust without confirmation")
+	profileCmd.AddCommand(allowCmd)
+	profileCmd.AddCommand(denyCmd)
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the secret cache",
+	Long: `Manage the cache of secret references having a ttl, e.g. ref+cmd://pass show vault#ttl=5m.
+Cached secrets are encrypted with a key kept in the runtime directory of the session.`,
+}
+
+// cacheClearCmd represents the cache clear command
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached secrets",
+	Long:  `Remove the cached secrets of all binaries: the next executions resolve their secret references again.`,
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache, err := setupSecretCache()
+		if err != nil {
+			return err
+		}
+
+		if err := cache.Clear(); err != nil {
+			return err
+		}
+
+		fmt.Println("✓ Secret cache cleared")
+
+		return nil
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheClearCmd)
+
+	// cacheCmd is added to the root command by Execute, only when no binary is given
+}
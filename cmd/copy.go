@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var copyForce bool
+
+// copyCmd represents the copy command
+var copyCmd = &cobra.Command{
+	Use:     "copy <name> <new-name>",
+	Aliases: []string{"cp"},
+	Short:   "Copy a profile",
+	Long:    `Copy a configuration profile under a new name, keeping its comments and formatting`,
+	Args:    cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profileName, newName := args[0], args[1]
+		binary := GetBinaryName()
+
+		if binary == "" {
+			return fmt.Errorf("binary name not specified")
+		}
+
+		service, err := getProfileService()
+		if err != nil {
+			return err
+		}
+
+		overwrite := false
+		if service.ProfileExists(newName, binary) {
+			if !copyForce && !confirm(fmt.Sprintf("Profile '%s' already exists for %s. Overwrite?", newName, binary)) {
+				fmt.Println("Aborted")
+				return nil
+			}
+			overwrite = true
+		}
+
+		if err := service.CopyProfile(profileName, newName, binary, overwrite); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Profile '%s' copied to '%s' for %s\n", profileName, newName, binary)
+
+		return nil
+	},
+}
+
+func init() {
+	copyCmd.Flags().BoolVarP(&copyForce, "force", "f", false, "Overwrite an existing profile without confirmation")
+	profileCmd.AddCommand(copyCmd)
+}
@@ -31,6 +31,7 @@ var createCmd = &cobra.Command{
 
 		fmt.Printf("✓ Profile '%s' created for %s\n", profileName, binary)
 		fmt.Printf("  Edit at: ~/.config/wrapper/%s/%s.env\n", binary, profileName)
+		fmt.Printf("  Or run: %s profile edit %s\n", binary, profileName)
 		fmt.Printf("  Set as current: %s profile set %s\n", binary, profileName)
 
 		return nil
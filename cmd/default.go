@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var defaultUnset bool
+
+// defaultCmd represents the default command
+var defaultCmd = &cobra.Command{
+	Use:   "default [name]",
+	Short: "Show, set or clear the default profile",
+	Long: `Show, set or clear the default profile.
+The default profile is used when no current profile is set.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		binary := GetBinaryName()
+
+		if binary == "" {
+			return fmt.Errorf("binary name not specified")
+		}
+
+		service, err := getProfileService()
+		if err != nil {
+			return err
+		}
+
+		if defaultUnset {
+			if len(args) > 0 {
+				return fmt.Errorf("--unset does not take a profile name")
+			}
+
+			if err := service.UnsetDefaultProfile(binary); err != nil {
+				return err
+			}
+
+			fmt.Printf("✓ Default profile cleared for %s\n", binary)
+			return nil
+		}
+
+		// Without a name, print the default profile
+		if len(args) == 0 {
+			profileName, err := service.GetDefaultProfile(binary)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println(profileName)
+			return nil
+		}
+
+		profileName := args[0]
+		if err := service.SetDefaultProfile(profileName, binary); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Default profile set to '%s' for %s\n", profileName, binary)
+
+		return nil
+	},
+}
+
+func init() {
+	defaultCmd.Flags().BoolVar(&defaultUnset, "unset", false, "Clear the default profile")
+	profileCmd.AddCommand(defaultCmd)
+}
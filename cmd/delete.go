@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var deleteForce bool
+
+// deleteCmd represents the delete command
+var deleteCmd = &cobra.Command{
+	Use:     "delete <name>",
+	Aliases: []string{"rm"},
+	Short:   "Delete a profile",
+	Long: `Delete a configuration profile.
+Deleting the current or default profile, or a profile extended by other
+profiles, requires --force. The current and default markers are then cleared.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profileName := args[0]
+		binary := GetBinaryName()
+
+		if binary == "" {
+			return fmt.Errorf("binary name not specified")
+		}
+
+		service, err := getProfileService()
+		if err != nil {
+			return err
+		}
+
+		if !deleteForce && !confirm(fmt.Sprintf("Delete profile '%s' for %s?", profileName, binary)) {
+			fmt.Println("Aborted")
+			return nil
+		}
+
+		if err := service.DeleteProfile(profileName, binary, deleteForce); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Profile '%s' deleted for %s\n", profileName, binary)
+
+		return nil
+	},
+}
+
+func init() {
+	deleteCmd.Flags().BoolVarP(&deleteForce, "force", "f", false, "Delete without confirmation, even if the profile is in use")
+	profileCmd.AddCommand(deleteCmd)
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/jycamier/wrapper/internal/domain"
+	"github.com/spf13/cobra"
+)
+
+// editCmd represents the edit command
+var editCmd = &cobra.Command{
+	Use:   "edit [name]",
+	Short: "Edit a profile in your editor",
+	Long: `Open a profile (the one executions use if no name is given) in $VISUAL or $EDITOR.
+The profile is edited on a temporary copy and only replaces the real file
+once it parses successfully. The copy of an encrypted profile is in clear text:
+it is kept in a private directory, under $XDG_RUNTIME_DIR when set, and removed
+once the editor exits.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		binary := GetBinaryName()
+
+		if binary == "" {
+			return fmt.Errorf("binary name not specified")
+		}
+
+		profileName := ""
+		if len(args) == 1 {
+			profileName = args[0]
+		}
+
+		service, err := getProfileService()
+		if err != nil {
+			return err
+		}
+
+		profileName, err = selectProfileName(service, profileName, binary)
+		if err != nil {
+			return err
+		}
+
+		profileName, original, err := service.ReadProfileContent(profileName, binary)
+		if err != nil {
+			return err
+		}
+
+		content := original
+		for {
+			content, err = editInEditor(profileName, content)
+			if err != nil {
+				return err
+			}
+
+			if bytes.Equal(content, original) {
+				fmt.Println("No changes")
+				return nil
+			}
+
+			err = service.WriteProfileContent(profileName, binary, content)
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, domain.ErrInvalidProfile) {
+				return err
+			}
+
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if !confirm("Edit again?") {
+				return fmt.Errorf("changes to profile '%s' discarded", profileName)
+			}
+		}
+
+		fmt.Printf("✓ Profile '%s' updated for %s\n", profileName, binary)
+
+		return nil
+	},
+}
+
+// editInEditor writes content to a temporary file, opens it in the user's
+// editor and returns the edited content. The file is created in a private
+// directory, under $XDG_RUNTIME_DIR when set, which is removed afterwards
+// along with any backup or swap file of the editor.
+func editInEditor(profileName string, content []byte) ([]byte, error) {
+	dir, err := os.MkdirTemp(os.Getenv("XDG_RUNTIME_DIR"), "wrapper-edit-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, profileName+".env")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	editor := strings.Fields(getEditor())
+	editorCmd := exec.Command(editor[0], append(editor[1:], path)...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+
+	if err := editorCmd.Run(); err != nil {
+		return nil, fmt.Errorf("editor exited with error: %w", err)
+	}
+
+	edited, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read temporary file: %w", err)
+	}
+
+	return edited, nil
+}
+
+// getEditor returns the editor command from $VISUAL or $EDITOR
+func getEditor() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(name)); editor != "" {
+			return editor
+		}
+	}
+	return "vi"
+}
+
+func init() {
+	profileCmd.AddCommand(editCmd)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// encryptCmd represents the encrypt command
+var encryptCmd = &cobra.Command{
+	Use:   "encrypt [name]",
+	Short: "Encrypt a profile at rest",
+	Long: `Encrypt a profile file (the one executions use if no name is given).
+The passphrase is read from WRAPPER_PASSPHRASE, the output of WRAPPER_PASSPHRASE_COMMAND
+or ~/.config/wrapper/key. Encrypted profiles are decrypted transparently when used.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		binary := GetBinaryName()
+
+		if binary == "" {
+			return fmt.Errorf("binary name not specified")
+		}
+
+		profileName := ""
+		if len(args) == 1 {
+			profileName = args[0]
+		}
+
+		service, err := getProfileService()
+		if err != nil {
+			return err
+		}
+
+		profileName, err = selectProfileName(service, profileName, binary)
+		if err != nil {
+			return err
+		}
+
+		profileName, err = service.EncryptProfile(profileName, binary)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Profile '%s' encrypted for %s\n", profileName, binary)
+
+		return nil
+	},
+}
+
+// decryptCmd represents the decrypt command
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt [name]",
+	Short: "Store an encrypted profile in plain text again",
+	Long:  `Decrypt a profile file (the one executions use if no name is given) and store it in plain text.`,
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		binary := GetBinaryName()
+
+		if binary == "" {
+			return fmt.Errorf("binary name not specified")
+		}
+
+		profileName := ""
+		if len(args) == 1 {
+			profileName = args[0]
+		}
+
+		service, err := getProfileService()
+		if err != nil {
+			return err
+		}
+
+		profileName, err = selectProfileName(service, profileName, binary)
+		if err != nil {
+			return err
+		}
+
+		profileName, err = service.DecryptProfile(profileName, binary)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Profile '%s' decrypted for %s\n", profileName, binary)
+
+		return nil
+	},
+}
+
+func init() {
+	profileCmd.AddCommand(encryptCmd)
+	profileCmd.AddCommand(decryptCmd)
+}
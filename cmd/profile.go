@@ -2,7 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/jycamier/wrapper/internal/application"
+	"github.com/jycamier/wrapper/internal/domain"
 	"github.com/spf13/cobra"
 )
 
@@ -59,41 +62,71 @@ var profileCmd = &cobra.Command{
 
 		fmt.Printf("%sProfiles for %s:%s\n", colorCyan, binary, colorReset)
 		for _, profile := range profiles {
-			envCount := len(profile.Environment())
 			name := profile.Name()
 
+			// Encrypted profiles are listed even when they can't be decrypted,
+			// refused ones even though they are not read
+			envCount := fmt.Sprintf("%d env vars", len(profile.Environment()))
+			if profile.IsInsecure() {
+				envCount = "refused: insecure permissions"
+			} else if profile.IsEncrypted() {
+				envCount = "encrypted"
+			}
+
 			// Determine display
 			if name == currentName && name == defaultName {
 				// Both current and default
-				fmt.Printf("  %s✓%s %s%s%s (%d env vars) %s[current, default]%s\n",
+				fmt.Printf("  %s✓%s %s%s%s (%s) %s[current, default]%s\n",
 					colorGreen, colorReset,
 					colorGreen, name, colorReset,
 					envCount,
 					colorYellow, colorReset)
 			} else if name == currentName {
 				// Current only
-				fmt.Printf("  %s✓%s %s%s%s (%d env vars) %s[current]%s\n",
+				fmt.Printf("  %s✓%s %s%s%s (%s) %s[current]%s\n",
 					colorGreen, colorReset,
 					colorGreen, name, colorReset,
 					envCount,
 					colorYellow, colorReset)
 			} else if name == defaultName {
 				// Default only
-				fmt.Printf("  %s●%s %s (%d env vars) %s[default]%s\n",
+				fmt.Printf("  %s●%s %s (%s) %s[default]%s\n",
 					colorYellow, colorReset,
 					name,
 					envCount,
 					colorYellow, colorReset)
 			} else {
 				// Regular profile
-				fmt.Printf("  - %s (%d env vars)\n", name, envCount)
+				fmt.Printf("  - %s (%s)\n", name, envCount)
 			}
 		}
 
+		// Session selection made with 'profile set --shell' overrides current.env
+		if sessionName := os.Getenv(domain.BinaryProfileEnvVar(binary)); sessionName != "" {
+			fmt.Printf("%sSession profile: %s%s\n", colorYellow, sessionName, colorReset)
+		}
+
 		return nil
 	},
 }
 
+// selectProfileName returns name, or the profile executions of the binary use
+// when it is empty, telling on stderr what selected it
+func selectProfileName(service *application.ProfileService, name, binary string) (string, error) {
+	if name != "" {
+		return name, nil
+	}
+
+	name, source, err := service.SelectProfile(binary)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(os.Stderr, "Using profile '%s' (selected by %s)\n", name, source)
+
+	return name, nil
+}
+
 func init() {
 	rootCmd.AddCommand(profileCmd)
 }
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var renameForce bool
+
+// renameCmd represents the rename command
+var renameCmd = &cobra.Command{
+	Use:     "rename <name> <new-name>",
+	Aliases: []string{"mv"},
+	Short:   "Rename a profile",
+	Long: `Rename a configuration profile.
+The current and default markers, and the profiles extending it, follow the new name.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		profileName, newName := args[0], args[1]
+		binary := GetBinaryName()
+
+		if binary == "" {
+			return fmt.Errorf("binary name not specified")
+		}
+
+		service, err := getProfileService()
+		if err != nil {
+			return err
+		}
+
+		overwrite := false
+		if service.ProfileExists(newName, binary) {
+			if !renameForce && !confirm(fmt.Sprintf("Profile '%s' already exists for %s. Overwrite?", newName, binary)) {
+				fmt.Println("Aborted")
+				return nil
+			}
+			overwrite = true
+		}
+
+		if err := service.RenameProfile(profileName, newName, binary, overwrite); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Profile '%s' renamed to '%s' for %s\n", profileName, newName, binary)
+
+		return nil
+	},
+}
+
+func init() {
+	renameCmd.Flags().BoolVarP(&renameForce, "force", "f", false, "Overwrite an existing profile without confirmation")
+	profileCmd.AddCommand(renameCmd)
+}
@@ -6,12 +6,25 @@ import (
 	"strings"
 
 	"github.com/jycamier/wrapper/internal/application"
+	"github.com/jycamier/wrapper/internal/domain"
 	"github.com/jycamier/wrapper/internal/infrastructure"
 	"github.com/spf13/cobra"
 )
 
 var (
-	binaryName string
+	binaryName  string
+	execOptions application.ExecuteOptions
+)
+
+const (
+	// profileFlag selects a profile for a single execution
+	profileFlag = "--wrapper-profile"
+	// yesFlag confirms the execution of a protected profile up front
+	yesFlag = "--wrapper-yes"
+	// envFlag sets a variable for a single execution, over the profile
+	envFlag = "--wrapper-env"
+	// dryRunFlag explains an execution without running the binary
+	dryRunFlag = "--wrapper-dry-run"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -47,13 +60,13 @@ Example:
 			return err
 		}
 
-		return service.Execute(binaryName, args)
+		return service.Execute(binaryName, args, execOptions)
 	},
 }
 
 // isWrapperCommand checks if the argument is a known wrapper command
 func isWrapperCommand(arg string) bool {
-	wrapperCommands := []string{"list", "alias", "version", "help", "--help", "-h", "completion"}
+	wrapperCommands := []string{"list", "alias", "cache", "version", "help", "--help", "-h", "completion"}
 	for _, cmd := range wrapperCommands {
 		if arg == cmd {
 			return true
@@ -62,6 +75,64 @@ func isWrapperCommand(arg string) bool {
 	return false
 }
 
+// extractWrapperFlags removes the wrapper's own execution flags from args so
+// they never reach the real binary. They are only read before the first
+// argument of the binary: everything from there on, "--" included, is passed
+// through untouched.
+func extractWrapperFlags(args []string) (application.ExecuteOptions, []string, error) {
+	var opts application.ExecuteOptions
+	remaining := make([]string, 0, len(args))
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == profileFlag:
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("flag %s requires a profile name", profileFlag)
+			}
+			opts.Profile = args[i+1]
+			i++
+		case strings.HasPrefix(arg, profileFlag+"="):
+			opts.Profile = strings.TrimPrefix(arg, profileFlag+"=")
+		case arg == yesFlag:
+			opts.AssumeYes = true
+		case arg == dryRunFlag:
+			opts.DryRun = true
+		case arg == envFlag:
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("flag %s requires a KEY=VALUE assignment", envFlag)
+			}
+			if err := addEnvOverride(&opts, args[i+1]); err != nil {
+				return opts, nil, err
+			}
+			i++
+		case strings.HasPrefix(arg, envFlag+"="):
+			if err := addEnvOverride(&opts, strings.TrimPrefix(arg, envFlag+"=")); err != nil {
+				return opts, nil, err
+			}
+		default:
+			return opts, append(remaining, args[i:]...), nil
+		}
+	}
+
+	return opts, remaining, nil
+}
+
+// addEnvOverride records a KEY=VALUE assignment given with --wrapper-env
+func addEnvOverride(opts *application.ExecuteOptions, assignment string) error {
+	key, value, ok := strings.Cut(assignment, "=")
+	if !ok || !domain.IsValidVariableName(key) {
+		return fmt.Errorf("invalid assignment '%s' for %s: expected KEY=VALUE", assignment, envFlag)
+	}
+
+	if opts.Env == nil {
+		opts.Env = make(map[string]string)
+	}
+	opts.Env[key] = value
+	return nil
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
 	// Determine binary name from invocation
@@ -70,6 +141,21 @@ func Execute() {
 		binaryName = os.Args[1]
 		// Remove binary name from args for cobra parsing
 		os.Args = append(os.Args[:1], os.Args[2:]...)
+
+		// Strip wrapper execution flags before cobra sees them
+		opts, args, err := extractWrapperFlags(os.Args[1:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		execOptions = opts
+		os.Args = append(os.Args[:1], args...)
+	}
+
+	// Commands that don't depend on a binary are only available without one,
+	// so that they never hide the subcommands of a wrapped binary
+	if binaryName == "" {
+		rootCmd.AddCommand(cacheCmd)
 	}
 
 	// Handle unknown command errors by executing the binary
@@ -85,7 +171,7 @@ func Execute() {
 			}
 
 			// Get remaining args after "wrapper <binary>"
-			if execErr := service.Execute(binaryName, os.Args[1:]); execErr != nil {
+			if execErr := service.Execute(binaryName, os.Args[1:], execOptions); execErr != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", execErr)
 				os.Exit(1)
 			}
@@ -100,6 +186,15 @@ func Execute() {
 // ExecuteWithBinary executes a command for a specific binary (used when invoked via symlink)
 func ExecuteWithBinary(binary string, args []string) {
 	binaryName = binary
+
+	// Strip wrapper execution flags before cobra sees them
+	opts, args, err := extractWrapperFlags(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	execOptions = opts
+
 	rootCmd.SetArgs(args)
 
 	if err := rootCmd.Execute(); err != nil {
@@ -113,7 +208,7 @@ func ExecuteWithBinary(binary string, args []string) {
 				os.Exit(1)
 			}
 
-			if execErr := service.Execute(binaryName, args); execErr != nil {
+			if execErr := service.Execute(binaryName, args, execOptions); execErr != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", execErr)
 				os.Exit(1)
 			}
@@ -132,7 +227,12 @@ func GetBinaryName() string {
 
 // setupRepository creates the profile repository
 func setupRepository() (*infrastructure.FilesystemRepository, error) {
-	return infrastructure.NewFilesystemRepository()
+	cipher, err := infrastructure.NewPassphraseCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	return infrastructure.NewFilesystemRepository(cipher)
 }
 
 // setupBinaryResolver creates the binary resolver
@@ -140,13 +240,44 @@ func setupBinaryResolver() (*infrastructure.PathBinaryResolver, error) {
 	return infrastructure.NewPathBinaryResolver()
 }
 
+// setupProjectConfigFinder creates the .wrapper file finder
+func setupProjectConfigFinder() (*infrastructure.FilesystemProjectConfigFinder, error) {
+	return infrastructure.NewFilesystemProjectConfigFinder()
+}
+
+// setupHookRepository creates the hook repository
+func setupHookRepository() (*infrastructure.FilesystemHookRepository, error) {
+	return infrastructure.NewFilesystemHookRepository()
+}
+
+// setupSecretCache creates the cache of secrets with a ttl
+func setupSecretCache() (*infrastructure.FileSecretCache, error) {
+	return infrastructure.NewFileSecretCache()
+}
+
+// setupSecretResolver creates the resolver of secret references
+func setupSecretResolver() (*domain.SecretResolver, error) {
+	cache, err := setupSecretCache()
+	if err != nil {
+		return nil, err
+	}
+
+	return infrastructure.NewSecretResolver(cache), nil
+}
+
 // getProfileService returns an initialized ProfileService
 func getProfileService() (*application.ProfileService, error) {
 	repo, err := setupRepository()
 	if err != nil {
 		return nil, err
 	}
-	return application.NewProfileService(repo), nil
+
+	projectConfigs, err := setupProjectConfigFinder()
+	if err != nil {
+		return nil, err
+	}
+
+	return application.NewProfileService(repo, projectConfigs), nil
 }
 
 // getExecutorService returns an initialized ExecutorService
@@ -161,7 +292,22 @@ func getExecutorService() (*application.ExecutorService, error) {
 		return nil, err
 	}
 
-	return application.NewExecutorService(repo, resolver), nil
+	projectConfigs, err := setupProjectConfigFinder()
+	if err != nil {
+		return nil, err
+	}
+
+	hookRepo, err := setupHookRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	secrets, err := setupSecretResolver()
+	if err != nil {
+		return nil, err
+	}
+
+	return application.NewExecutorService(repo, resolver, projectConfigs, hookRepo, secrets), nil
 }
 
 func init() {
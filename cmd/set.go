@@ -2,16 +2,28 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/jycamier/wrapper/internal/domain"
 	"github.com/spf13/cobra"
 )
 
+var (
+	setShell  string
+	setGlobal bool
+)
+
 // setCmd represents the set command
 var setCmd = &cobra.Command{
 	Use:   "set <name>",
 	Short: "Set the current profile",
-	Long:  `Set the current configuration profile`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Set the current configuration profile.
+
+With --shell, the profile is selected for the calling shell session only:
+shell code exporting WRAPPER_PROFILE_<BINARY> is printed on stdout, to be
+evaluated by the shell functions generated with 'wrapper alias --session'.
+Add --global to update current.env instead and drop the session selection.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		profileName := args[0]
 		binary := GetBinaryName()
@@ -25,6 +37,30 @@ var setCmd = &cobra.Command{
 			return err
 		}
 
+		// Session mode: emit shell code, messages go to stderr
+		if setShell != "" {
+			if err := validateShell(setShell); err != nil {
+				return err
+			}
+
+			if setGlobal {
+				if err := service.SetCurrentProfile(profileName, binary); err != nil {
+					return err
+				}
+				fmt.Println(shellUnset(setShell, domain.BinaryProfileEnvVar(binary)))
+				fmt.Fprintf(os.Stderr, "✓ Current profile set to '%s' for %s\n", profileName, binary)
+				return nil
+			}
+
+			envVar, err := service.SessionProfileVariable(profileName, binary)
+			if err != nil {
+				return err
+			}
+			fmt.Println(shellExport(setShell, envVar, profileName))
+			fmt.Fprintf(os.Stderr, "✓ Session profile set to '%s' for %s\n", profileName, binary)
+			return nil
+		}
+
 		if err := service.SetCurrentProfile(profileName, binary); err != nil {
 			return err
 		}
@@ -36,5 +72,7 @@ var setCmd = &cobra.Command{
 }
 
 func init() {
+	setCmd.Flags().StringVar(&setShell, "shell", "", "Print shell code selecting the profile for the current session (bash, zsh, fish)")
+	setCmd.Flags().BoolVar(&setGlobal, "global", false, "With --shell, set current.env for all sessions instead")
 	profileCmd.AddCommand(setCmd)
 }
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// supportedShells lists the shells wrapper can emit code for
+var supportedShells = []string{"bash", "zsh", "fish"}
+
+// validateShell checks that code can be emitted for a shell
+func validateShell(shell string) error {
+	for _, supported := range supportedShells {
+		if shell == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported shell '%s': expected one of %v", shell, supportedShells)
+}
+
+// shellExport returns the code exporting a variable in the given shell
+func shellExport(shell, name, value string) string {
+	if shell == "fish" {
+		return fmt.Sprintf("set -gx %s %s", name, shellQuote(value))
+	}
+	return fmt.Sprintf("export %s=%s", name, shellQuote(value))
+}
+
+// shellUnset returns the code removing a variable in the given shell
+func shellUnset(shell, name string) string {
+	if shell == "fish" {
+		return fmt.Sprintf("set -e %s", name)
+	}
+	return fmt.Sprintf("unset %s", name)
+}
+
+// shellQuote single-quotes a value for POSIX shells and fish
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
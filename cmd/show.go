@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jycamier/wrapper/internal/application"
+	"github.com/jycamier/wrapper/internal/domain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	showReveal   bool
+	showResolved bool
+)
+
+// showCmd represents the show command
+var showCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show the variables of a profile",
+	Long: `Show the variables of a profile (the one executions use if no name is given).
+Values are masked unless --reveal is given.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		binary := GetBinaryName()
+
+		if binary == "" {
+			return fmt.Errorf("binary name not specified")
+		}
+
+		profileName := ""
+		if len(args) == 1 {
+			profileName = args[0]
+		}
+
+		service, err := getProfileService()
+		if err != nil {
+			return err
+		}
+
+		profileName, err = selectProfileName(service, profileName, binary)
+		if err != nil {
+			return err
+		}
+
+		profile, err := service.GetProfile(profileName, binary)
+		if err != nil {
+			return err
+		}
+		own := profile.Environment()
+		ownDirectives := profile.Directives()
+
+		env := own
+		directives := ownDirectives
+		if showResolved {
+			resolved, err := service.GetResolvedProfile(profile.Name(), binary)
+			if err != nil {
+				return err
+			}
+			env = resolved.Environment()
+			directives = resolved.Directives()
+		}
+
+		extends := ""
+		if profile.Parent() != "" {
+			extends = fmt.Sprintf(" (extends %s)", profile.Parent())
+		}
+		fmt.Printf("%sProfile %s for %s%s:%s\n", colorCyan, profile.Name(), binary, extends, colorReset)
+
+		if len(env) == 0 && len(directives) == 0 {
+			fmt.Println("  (no env vars)")
+			return nil
+		}
+
+		keys := make([]string, 0, len(env))
+		for key := range env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value := env[key]
+			if !showReveal {
+				value = application.MaskValue(value)
+			}
+
+			if _, ok := own[key]; ok {
+				fmt.Printf("  %s=%s\n", key, value)
+			} else {
+				fmt.Printf("  %s=%s %s(inherited)%s\n", key, value, colorYellow, colorReset)
+			}
+		}
+
+		for _, directive := range directives {
+			line := formatDirective(directive, showReveal)
+			if containsDirective(ownDirectives, directive) {
+				fmt.Printf("  %s\n", line)
+			} else {
+				fmt.Printf("  %s %s(inherited)%s\n", line, colorYellow, colorReset)
+			}
+		}
+
+		return nil
+	},
+}
+
+// formatDirective renders a directive as written in a profile
+func formatDirective(directive domain.Directive, reveal bool) string {
+	value := directive.Value()
+	if !reveal {
+		value = application.MaskValue(value)
+	}
+
+	switch directive.Kind() {
+	case domain.DirectiveAppend:
+		return fmt.Sprintf("%s+=%s", directive.Key(), value)
+	case domain.DirectivePrepend:
+		return fmt.Sprintf("%s^=%s", directive.Key(), value)
+	default:
+		return fmt.Sprintf("unset %s", directive.Key())
+	}
+}
+
+// containsDirective reports whether a directive is part of a list
+func containsDirective(directives []domain.Directive, directive domain.Directive) bool {
+	for _, d := range directives {
+		if d == directive {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	showCmd.Flags().BoolVar(&showReveal, "reveal", false, "Show values in clear text")
+	showCmd.Flags().BoolVar(&showResolved, "resolved", false, "Include values inherited from extended profiles")
+	profileCmd.AddCommand(showCmd)
+}
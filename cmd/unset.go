@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jycamier/wrapper/internal/domain"
+	"github.com/spf13/cobra"
+)
+
+var unsetShell string
+
+// unsetCmd represents the unset command
+var unsetCmd = &cobra.Command{
+	Use:   "unset",
+	Short: "Clear the current profile",
+	Long: `Clear the current profile.
+Execution then falls back to the default profile, if one is set.
+
+With --shell, only the session selection made with 'profile set --shell'
+is cleared: shell code removing WRAPPER_PROFILE_<BINARY> is printed on stdout.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		binary := GetBinaryName()
+
+		if binary == "" {
+			return fmt.Errorf("binary name not specified")
+		}
+
+		// Session mode: emit shell code, messages go to stderr
+		if unsetShell != "" {
+			if err := validateShell(unsetShell); err != nil {
+				return err
+			}
+			fmt.Println(shellUnset(unsetShell, domain.BinaryProfileEnvVar(binary)))
+			fmt.Fprintf(os.Stderr, "✓ Session profile cleared for %s\n", binary)
+			return nil
+		}
+
+		service, err := getProfileService()
+		if err != nil {
+			return err
+		}
+
+		if err := service.UnsetCurrentProfile(binary); err != nil {
+			return err
+		}
+
+		fmt.Printf("✓ Current profile cleared for %s\n", binary)
+
+		if defaultName, err := service.GetDefaultProfile(binary); err == nil {
+			fmt.Printf("  Falling back to default profile '%s'\n", defaultName)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	unsetCmd.Flags().StringVar(&unsetShell, "shell", "", "Print shell code clearing the session profile (bash, zsh, fish)")
+	profileCmd.AddCommand(unsetCmd)
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var varProfile string
+
+// varCmd represents the var command
+var varCmd = &cobra.Command{
+	Use:   "var",
+	Short: "Manage the variables of a profile",
+	Long: `Manage the variables of a profile.
+Commands operate on the profile executions use unless --profile is given.`,
+}
+
+// varSetCmd represents the var set command
+var varSetCmd = &cobra.Command{
+	Use:   "set <KEY=VALUE>...",
+	Short: "Set variables in a profile",
+	Long:  `Set one or more variables in a profile, keeping its comments and ordering`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		binary := GetBinaryName()
+
+		if binary == "" {
+			return fmt.Errorf("binary name not specified")
+		}
+
+		service, err := getProfileService()
+		if err != nil {
+			return err
+		}
+
+		profileName, err := selectProfileName(service, varProfile, binary)
+		if err != nil {
+			return err
+		}
+
+		for _, arg := range args {
+			key, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				return fmt.Errorf("invalid assignment '%s': expected KEY=VALUE", arg)
+			}
+
+			if _, err := service.SetVariable(profileName, binary, key, value); err != nil {
+				return err
+			}
+
+			fmt.Printf("✓ %s set in profile '%s' for %s\n", key, profileName, binary)
+		}
+
+		return nil
+	},
+}
+
+// varGetCmd represents the var get command
+var varGetCmd = &cobra.Command{
+	Use:   "get <KEY>",
+	Short: "Print the value of a variable",
+	Long:  `Print the value of a variable of a profile, including inherited values`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		binary := GetBinaryName()
+
+		if binary == "" {
+			return fmt.Errorf("binary name not specified")
+		}
+
+		service, err := getProfileService()
+		if err != nil {
+			return err
+		}
+
+		profileName, err := selectProfileName(service, varProfile, binary)
+		if err != nil {
+			return err
+		}
+
+		value, err := service.GetVariable(profileName, binary, args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(value)
+
+		return nil
+	},
+}
+
+// varUnsetCmd represents the var unset command
+var varUnsetCmd = &cobra.Command{
+	Use:   "unset <KEY>...",
+	Short: "Remove variables from a profile",
+	Long:  `Remove one or more variables from a profile`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		binary := GetBinaryName()
+
+		if binary == "" {
+			return fmt.Errorf("binary name not specified")
+		}
+
+		service, err := getProfileService()
+		if err != nil {
+			return err
+		}
+
+		profileName, err := selectProfileName(service, varProfile, binary)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range args {
+			if _, err := service.UnsetVariable(profileName, binary, key); err != nil {
+				return err
+			}
+
+			fmt.Printf("✓ %s removed from profile '%s' for %s\n", key, profileName, binary)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	varCmd.PersistentFlags().StringVarP(&varProfile, "profile", "p", "", "Profile to operate on (defaults to the one executions use)")
+	varCmd.AddCommand(varSetCmd)
+	varCmd.AddCommand(varGetCmd)
+	varCmd.AddCommand(varUnsetCmd)
+	profileCmd.AddCommand(varCmd)
+}
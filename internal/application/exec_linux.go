@@ -0,0 +1,15 @@
+//go:build linux
+
+package application
+
+import "syscall"
+
+// execReplaceSupported reports whether execReplace can be used on this platform
+const execReplaceSupported = true
+
+// execReplace replaces the current process with the binary. It only returns
+// when the binary could not be executed.
+func execReplace(binaryPath string, args, environ []string) error {
+	argv := append([]string{binaryPath}, args...)
+	return syscall.Exec(binaryPath, argv, environ)
+}
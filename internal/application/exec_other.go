@@ -0,0 +1,13 @@
+//go:build !linux
+
+package application
+
+import "errors"
+
+// execReplaceSupported reports whether execReplace can be used on this platform
+const execReplaceSupported = false
+
+// execReplace is not supported on this platform, binaries are always forked
+func execReplace(binaryPath string, args, environ []string) error {
+	return errors.New("replacing the wrapper process is not supported on this platform")
+}
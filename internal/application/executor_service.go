@@ -12,25 +12,53 @@ import (
 type ExecutorService struct {
 	profileRepo    domain.ProfileRepository
 	binaryResolver domain.BinaryResolver
+	selector       *profileSelector
+	hookRepo       domain.HookRepository
+	secrets        *domain.SecretResolver
 }
 
 // NewExecutorService creates a new ExecutorService
-func NewExecutorService(profileRepo domain.ProfileRepository, binaryResolver domain.BinaryResolver) *ExecutorService {
+func NewExecutorService(profileRepo domain.ProfileRepository, binaryResolver domain.BinaryResolver, projectConfigs domain.ProjectConfigFinder, hookRepo domain.HookRepository, secrets *domain.SecretResolver) *ExecutorService {
 	return &ExecutorService{
 		profileRepo:    profileRepo,
 		binaryResolver: binaryResolver,
+		selector:       &profileSelector{repo: profileRepo, projectConfigs: projectConfigs},
+		hookRepo:       hookRepo,
+		secrets:        secrets,
 	}
 }
 
+// ExecMode selects how the real binary is started
+type ExecMode string
+
+const (
+	// ExecModeReplace replaces the wrapper process with the binary (execve)
+	ExecModeReplace ExecMode = "exec"
+	// ExecModeFork runs the binary as a child of the wrapper process
+	ExecModeFork ExecMode = "fork"
+
+	// ExecModeEnvVar forces an execution mode
+	ExecModeEnvVar = "WRAPPER_EXEC_MODE"
+)
+
+// ExecuteOptions holds per-invocation settings of an execution
+type ExecuteOptions struct {
+	// Profile overrides the active profile for this execution only
+	Profile string
+	// AssumeYes skips the confirmation of protected profiles
+	AssumeYes bool
+	// Env holds variables taking precedence over the profile and the process
+	// environment
+	Env map[string]string
+	// DryRun explains the execution instead of running the binary
+	DryRun bool
+}
+
 // Execute executes a binary with the active profile environment
-func (s *ExecutorService) Execute(binaryName string, args []string) error {
-	// Get active profile
-	profile, err := s.profileRepo.GetActiveProfile(binaryName)
+func (s *ExecutorService) Execute(binaryName string, args []string, opts ExecuteOptions) error {
+	profile, source, err := s.selectProfile(binaryName, opts)
 	if err != nil {
-		if err == domain.ErrNoCurrentProfile || err == domain.ErrNoDefaultProfile {
-			return fmt.Errorf("no active profile for '%s': use '%s profile create <name>' to create one", binaryName, binaryName)
-		}
-		return fmt.Errorf("failed to get active profile: %w", err)
+		return err
 	}
 
 	// Resolve real binary path
@@ -42,28 +70,135 @@ func (s *ExecutorService) Execute(binaryName string, args []string) error {
 		return fmt.Errorf("failed to resolve binary: %w", err)
 	}
 
-	// Prepare command
-	cmd := exec.Command(binaryPath, args...)
+	// Protected profiles require a confirmation, a dry run only reports it
+	confirm := profile.IsProtected() && requiresConfirmation(profile, binaryName, args)
+	if !opts.DryRun {
+		if err := confirmProtected(profile, binaryName, args, opts.AssumeYes); err != nil {
+			return err
+		}
+	}
+
+	// Inject the profile arguments
+	args, err = profile.Arguments(args)
+	if err != nil {
+		return fmt.Errorf("invalid arguments in profile '%s': %w", profile.Name(), err)
+	}
 
-	// Set environment variables from profile
-	cmd.Env = os.Environ()
-	for key, value := range profile.Environment() {
-		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	// A dry run doesn't run secret commands, references are shown unresolved
+	secrets := s.secrets
+	if opts.DryRun {
+		secrets = nil
 	}
 
+	// Build the environment: process, then profile, then one-shot overrides
+	environ, err := domain.BuildEnvironment(os.Environ(), profile, opts.Env, secrets)
+	if err != nil {
+		return fmt.Errorf("failed to build environment of profile '%s': %w", profile.Name(), err)
+	}
+
+	// Load hooks
+	hooks := &hookRun{binaryName: binaryName, profileName: profile.Name(), args: args, environ: environ}
+	if err := hooks.load(s.hookRepo); err != nil {
+		return err
+	}
+
+	// Post hooks need the wrapper to outlive the binary
+	mode := s.execMode()
+	if len(hooks.post) > 0 {
+		mode = ExecModeFork
+	}
+
+	if opts.DryRun {
+		plan := &executionPlan{
+			binaryPath: binaryPath,
+			profile:    profile,
+			source:     source,
+			args:       args,
+			mode:       mode,
+			confirm:    confirm,
+			hooks:      hooks,
+			before:     os.Environ(),
+			after:      environ,
+		}
+		plan.print(os.Stdout)
+		return nil
+	}
+
+	// Pre hooks can abort the execution
+	if err := hooks.runPre(); err != nil {
+		return err
+	}
+
+	if mode == ExecModeReplace {
+		// Only returns if the binary could not be started
+		if err := execReplace(binaryPath, args, environ); err != nil {
+			return fmt.Errorf("failed to execute binary: %w", err)
+		}
+	}
+
+	status, err := s.fork(binaryPath, args, environ)
+	if err != nil {
+		return err
+	}
+
+	hooks.runPost(status.exitCode())
+
+	// Preserve exit code, or the signal that terminated the child
+	if !status.success() {
+		exitLikeChild(status)
+	}
+
+	return nil
+}
+
+// fork runs the binary as a child process and returns how it terminated
+func (s *ExecutorService) fork(binaryPath string, args, environ []string) (*childStatus, error) {
+	// Prepare command
+	cmd := exec.Command(binaryPath, args...)
+	cmd.Env = environ
+
 	// Wire up stdio
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	// Execute
-	if err := cmd.Run(); err != nil {
-		// Preserve exit code
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			os.Exit(exitErr.ExitCode())
+	// Execute, relaying signals sent to the wrapper while the child runs
+	status, err := runChild(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute binary: %w", err)
+	}
+
+	return status, nil
+}
+
+// execMode returns how the binary should be started. Replacing the wrapper
+// process is the default where supported; WRAPPER_EXEC_MODE=fork keeps the
+// wrapper as the parent process.
+func (s *ExecutorService) execMode() ExecMode {
+	if !execReplaceSupported || ExecMode(os.Getenv(ExecModeEnvVar)) == ExecModeFork {
+		return ExecModeFork
+	}
+	return ExecModeReplace
+}
+
+// selectProfile returns the profile to execute with and why it was selected,
+// see profileSelector for the order of precedence
+func (s *ExecutorService) selectProfile(binaryName string, opts ExecuteOptions) (*domain.Profile, string, error) {
+	name, source, err := s.selector.selectName(binaryName, opts.Profile, "command line flag")
+	if err != nil {
+		if err == domain.ErrNoCurrentProfile {
+			return nil, "", fmt.Errorf("no active profile for '%s': use '%s profile create <name>' to create one", binaryName, binaryName)
 		}
-		return fmt.Errorf("failed to execute binary: %w", err)
+		return nil, "", fmt.Errorf("failed to get active profile: %w", err)
 	}
 
-	return nil
+	profile, err := s.profileRepo.Resolve(name, binaryName)
+	if err != nil {
+		if err == domain.ErrProfileNotFound {
+			return nil, "", fmt.Errorf("profile '%s' not found for binary '%s'", name, binaryName)
+		}
+		return nil, "", fmt.Errorf("failed to get profile '%s': %w", name, err)
+	}
+
+	return profile, source, nil
 }
@@ -0,0 +1,106 @@
+package application
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jycamier/wrapper/internal/domain"
+)
+
+// executionPlan describes an execution without running it
+type executionPlan struct {
+	binaryPath string
+	profile    *domain.Profile
+	source     string
+	args       []string
+	mode       ExecMode
+	confirm    bool
+	hooks      *hookRun
+	before     []string
+	after      []string
+}
+
+// print writes the plan in a human readable form. Values are masked.
+func (p *executionPlan) print(w io.Writer) {
+	profile := p.profile.Name()
+	if p.profile.Parent() != "" {
+		profile += fmt.Sprintf(" (extends %s)", p.profile.Parent())
+	}
+
+	argv := make([]string, 0, len(p.args)+1)
+	for _, arg := range append([]string{p.binaryPath}, p.args...) {
+		argv = append(argv, quoteArg(arg))
+	}
+
+	fmt.Fprintf(w, "Binary:      %s\n", p.binaryPath)
+	fmt.Fprintf(w, "Profile:     %s\n", profile)
+	fmt.Fprintf(w, "Selected by: %s\n", p.source)
+	fmt.Fprintf(w, "Command:     %s\n", strings.Join(argv, " "))
+	fmt.Fprintf(w, "Mode:        %s\n", p.mode)
+	if p.confirm {
+		fmt.Fprintf(w, "Protected:   confirmation required\n")
+	}
+
+	if len(p.hooks.pre)+len(p.hooks.post) > 0 {
+		fmt.Fprintln(w, "Hooks:")
+		hooks := append(append([]*domain.Hook{}, p.hooks.pre...), p.hooks.post...)
+		for _, hook := range hooks {
+			fmt.Fprintf(w, "  %-4s %s\n", hook.Stage(), hook.Path())
+		}
+	}
+
+	fmt.Fprintln(w, "Environment:")
+	if !p.printEnvironmentDiff(w) {
+		fmt.Fprintln(w, "  (unchanged)")
+	}
+}
+
+// printEnvironmentDiff writes the variables added (+), changed (~) and
+// removed (-) compared to the process environment. It reports whether there
+// was any difference.
+func (p *executionPlan) printEnvironmentDiff(w io.Writer) bool {
+	before := domain.EnvironMap(p.before)
+	after := domain.EnvironMap(p.after)
+
+	keys := make([]string, 0, len(before)+len(after))
+	for key := range after {
+		keys = append(keys, key)
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changed := false
+	for _, key := range keys {
+		oldValue, wasSet := before[key]
+		newValue, isSet := after[key]
+
+		switch {
+		case !wasSet:
+			fmt.Fprintf(w, "  + %s=%s\n", key, MaskValue(newValue))
+		case !isSet:
+			fmt.Fprintf(w, "  - %s\n", key)
+		case oldValue != newValue:
+			fmt.Fprintf(w, "  ~ %s=%s (was %s)\n", key, MaskValue(newValue), MaskValue(oldValue))
+		default:
+			continue
+		}
+		changed = true
+	}
+
+	return changed
+}
+
+// quoteArg quotes an argument when it would be split or misread by a shell
+func quoteArg(arg string) string {
+	if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$`;&|<>()*?[]#~") {
+		return strconv.Quote(arg)
+	}
+	return arg
+}
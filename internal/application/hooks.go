@@ -0,0 +1,107 @@
+package application
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/jycamier/wrapper/internal/domain"
+)
+
+// Variables describing the execution, passed to hooks on top of the profile environment
+const (
+	hookBinaryEnvVar   = "WRAPPER_BINARY"
+	hookProfileEnvVar  = "WRAPPER_ACTIVE_PROFILE"
+	hookStageEnvVar    = "WRAPPER_HOOK_STAGE"
+	hookExitCodeEnvVar = "WRAPPER_EXIT_CODE"
+)
+
+// hookRun holds the hooks of a single execution and the context passed to them
+type hookRun struct {
+	binaryName  string
+	profileName string
+	args        []string
+	environ     []string
+	pre         []*domain.Hook
+	post        []*domain.Hook
+}
+
+// load finds the pre and post hooks of the execution. Hooks are skipped when
+// the wrapper is invoked from a hook, so a hook can call the wrapped binary.
+func (h *hookRun) load(repo domain.HookRepository) error {
+	if repo == nil || os.Getenv(hookStageEnvVar) != "" {
+		return nil
+	}
+
+	var err error
+	if h.pre, err = repo.FindHooks(h.binaryName, h.profileName, domain.HookStagePre); err != nil {
+		return fmt.Errorf("failed to load pre hooks: %w", err)
+	}
+	if h.post, err = repo.FindHooks(h.binaryName, h.profileName, domain.HookStagePost); err != nil {
+		return fmt.Errorf("failed to load post hooks: %w", err)
+	}
+
+	return nil
+}
+
+// runPre runs the pre hooks in order. A hook exiting with a non-zero code
+// aborts the execution: the wrapper exits with that code.
+func (h *hookRun) runPre() error {
+	for _, hook := range h.pre {
+		code, err := h.run(hook, nil)
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			fmt.Fprintf(os.Stderr, "wrapper: pre hook %s exited with code %d, aborting %s\n",
+				filepath.Base(hook.Path()), code, h.binaryName)
+			os.Exit(code)
+		}
+	}
+	return nil
+}
+
+// runPost runs the post hooks in order. Failures are reported but don't
+// change the exit code of the binary.
+func (h *hookRun) runPost(exitCode int) {
+	extra := []string{hookExitCodeEnvVar + "=" + strconv.Itoa(exitCode)}
+
+	for _, hook := range h.post {
+		code, err := h.run(hook, extra)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "wrapper: %v\n", err)
+			continue
+		}
+		if code != 0 {
+			fmt.Fprintf(os.Stderr, "wrapper: post hook %s exited with code %d\n", filepath.Base(hook.Path()), code)
+		}
+	}
+}
+
+// run executes a hook with the resolved environment and the binary arguments,
+// and returns its exit code
+func (h *hookRun) run(hook *domain.Hook, extra []string) (int, error) {
+	cmd := exec.Command(hook.Path(), h.args...)
+	cmd.Env = append(append([]string{}, h.environ...),
+		hookBinaryEnvVar+"="+h.binaryName,
+		hookProfileEnvVar+"="+h.profileName,
+		hookStageEnvVar+"="+string(hook.Stage()),
+	)
+	cmd.Env = append(cmd.Env, extra...)
+
+	// Hooks may be interactive, e.g. to log in
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitStatus(exitErr.ProcessState), nil
+		}
+		return 0, fmt.Errorf("failed to run %s hook %s: %w", hook.Stage(), hook.Path(), err)
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,15 @@
+package application
+
+import "strings"
+
+// MaskValue hides a value for display, keeping only a short prefix on long
+// values so that different secrets can still be told apart
+func MaskValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) < 12 {
+		return "****"
+	}
+	return value[:3] + strings.Repeat("*", 8)
+}
@@ -0,0 +1,108 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jycamier/wrapper/internal/domain"
+)
+
+// Sources of the selected profile, see profileSelector
+const (
+	sourceCurrentProfile = "current profile"
+	sourceDefaultProfile = "default profile"
+)
+
+// profileSelector picks the profile used for a binary. Executions and the
+// profile commands go through the same selector so they always agree on the
+// profile in use.
+type profileSelector struct {
+	repo           domain.ProfileRepository
+	projectConfigs domain.ProjectConfigFinder
+}
+
+// selectName returns the name of the profile selected for a binary and why it
+// was selected. An explicit name given by source takes precedence over
+// WRAPPER_PROFILE_<BINARY> / WRAPPER_PROFILE, then over a trusted .wrapper
+// file, then over the current profile and finally the default profile.
+// It returns domain.ErrNoCurrentProfile when no profile is selected.
+func (s *profileSelector) selectName(binaryName, name, source string) (string, string, error) {
+	if name != "" {
+		return name, source, nil
+	}
+
+	variable := domain.BinaryProfileEnvVar(binaryName)
+	if name := os.Getenv(variable); name != "" {
+		return name, variable + " environment variable", nil
+	}
+	if name := os.Getenv(domain.ProfileEnvVar); name != "" {
+		return name, domain.ProfileEnvVar + " environment variable", nil
+	}
+
+	if name, path := s.projectProfile(binaryName); name != "" {
+		return name, path, nil
+	}
+
+	// Only a missing profile falls back to the next one: a profile that can't
+	// be read (no key, refused, invalid) is reported rather than replaced
+	profile, err := s.repo.GetCurrent(binaryName)
+	if err == nil {
+		return profile.Name(), sourceCurrentProfile, nil
+	}
+	if !isMissingProfile(err) {
+		return "", "", fmt.Errorf("failed to read the current profile: %w", err)
+	}
+
+	profile, err = s.repo.GetDefault(binaryName)
+	if err == nil {
+		return profile.Name(), sourceDefaultProfile, nil
+	}
+	if !isMissingProfile(err) {
+		return "", "", fmt.Errorf("failed to read the default profile: %w", err)
+	}
+
+	return "", "", domain.ErrNoCurrentProfile
+}
+
+// isMissingProfile reports whether the current or default profile is not set,
+// or points to a profile that doesn't exist anymore
+func isMissingProfile(err error) bool {
+	return errors.Is(err, domain.ErrNoCurrentProfile) ||
+		errors.Is(err, domain.ErrNoDefaultProfile) ||
+		errors.Is(err, domain.ErrProfileNotFound)
+}
+
+// projectProfile returns the profile pinned for a binary by the nearest
+// trusted .wrapper file and the path of that file, or "" if there is none
+func (s *profileSelector) projectProfile(binaryName string) (string, string) {
+	if s.projectConfigs == nil {
+		return "", ""
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", ""
+	}
+
+	config, err := s.projectConfigs.Find(dir)
+	if err != nil {
+		if err != domain.ErrProjectConfigNotFound {
+			fmt.Fprintf(os.Stderr, "wrapper: ignoring project configuration: %v\n", err)
+		}
+		return "", ""
+	}
+
+	profileName, ok := config.ProfileFor(binaryName)
+	if !ok {
+		return "", ""
+	}
+
+	if !config.IsTrusted() {
+		fmt.Fprintf(os.Stderr, "wrapper: %s pins %s to '%s' but is not trusted, ignoring it (run '%s profile allow' to trust it)\n",
+			config.Path(), binaryName, profileName, binaryName)
+		return "", ""
+	}
+
+	return profileName, config.Path()
+}
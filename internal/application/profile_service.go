@@ -8,19 +8,29 @@ import (
 
 // ProfileService handles profile-related use cases
 type ProfileService struct {
-	repo domain.ProfileRepository
+	repo     domain.ProfileRepository
+	selector *profileSelector
 }
 
-// NewProfileService creates a new ProfileService
-func NewProfileService(repo domain.ProfileRepository) *ProfileService {
-	return &ProfileService{repo: repo}
+// NewProfileService creates a new ProfileService. The project configuration
+// finder may be nil.
+func NewProfileService(repo domain.ProfileRepository, projectConfigs domain.ProjectConfigFinder) *ProfileService {
+	return &ProfileService{
+		repo:     repo,
+		selector: &profileSelector{repo: repo, projectConfigs: projectConfigs},
+	}
 }
 
 // CreateProfile creates a new profile
 func (s *ProfileService) CreateProfile(name, binaryName string) error {
-	// Check if profile already exists
-	existing, err := s.repo.FindByName(name, binaryName)
-	if err == nil && existing != nil {
+	unlock, err := s.repo.Lock(binaryName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Check if the profile file already exists, even one that can't be read
+	if s.repo.Exists(name, binaryName) {
 		return fmt.Errorf("profile '%s' for binary '%s' already exists", name, binaryName)
 	}
 
@@ -66,6 +76,20 @@ func (s *ProfileService) SetCurrentProfile(name, binaryName string) error {
 	return nil
 }
 
+// SessionProfileVariable verifies a profile exists and returns the environment
+// variable a shell session exports to select it without touching current.env
+func (s *ProfileService) SessionProfileVariable(name, binaryName string) (string, error) {
+	// Verify profile exists
+	if _, err := s.repo.FindByName(name, binaryName); err != nil {
+		if err == domain.ErrProfileNotFound {
+			return "", fmt.Errorf("profile '%s' not found for binary '%s'", name, binaryName)
+		}
+		return "", fmt.Errorf("failed to find profile: %w", err)
+	}
+
+	return domain.BinaryProfileEnvVar(binaryName), nil
+}
+
 // GetCurrentProfile gets the current profile name for a binary
 func (s *ProfileService) GetCurrentProfile(binaryName string) (string, error) {
 	profile, err := s.repo.GetCurrent(binaryName)
@@ -98,6 +122,36 @@ func (s *ProfileService) SetDefaultProfile(name, binaryName string) error {
 	return nil
 }
 
+// UnsetCurrentProfile clears the current profile for a binary, so that the
+// default profile is used instead
+func (s *ProfileService) UnsetCurrentProfile(binaryName string) error {
+	if err := s.repo.UnsetCurrent(binaryName); err != nil {
+		return fmt.Errorf("failed to unset current profile: %w", err)
+	}
+	return nil
+}
+
+// GetDefaultProfile gets the default profile name for a binary
+func (s *ProfileService) GetDefaultProfile(binaryName string) (string, error) {
+	profile, err := s.repo.GetDefault(binaryName)
+	if err != nil {
+		if err == domain.ErrNoDefaultProfile {
+			return "", fmt.Errorf("no default profile set for binary '%s'", binaryName)
+		}
+		return "", fmt.Errorf("failed to get default profile: %w", err)
+	}
+
+	return profile.Name(), nil
+}
+
+// UnsetDefaultProfile clears the default profile for a binary
+func (s *ProfileService) UnsetDefaultProfile(binaryName string) error {
+	if err := s.repo.UnsetDefault(binaryName); err != nil {
+		return fmt.Errorf("failed to unset default profile: %w", err)
+	}
+	return nil
+}
+
 // GetActiveProfile gets the active profile (current if set, otherwise default)
 func (s *ProfileService) GetActiveProfile(binaryName string) (*domain.Profile, error) {
 	profile, err := s.repo.GetActiveProfile(binaryName)
@@ -110,3 +164,414 @@ func (s *ProfileService) GetActiveProfile(binaryName string) (*domain.Profile, e
 
 	return profile, nil
 }
+
+// DeleteProfile deletes a profile. Deleting the current or default profile, or a
+// profile extended by other profiles, is refused unless force is set. The
+// profiles of the binary are locked from the checks to the markers update.
+func (s *ProfileService) DeleteProfile(name, binaryName string, force bool) error {
+	unlock, err := s.repo.Lock(binaryName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Verify the profile file exists, a profile that can't be read can still be deleted
+	if !s.repo.Exists(name, binaryName) {
+		return fmt.Errorf("profile '%s' not found for binary '%s'", name, binaryName)
+	}
+
+	isCurrent := s.currentName(binaryName) == name
+	isDefault := s.defaultName(binaryName) == name
+	children := s.childrenOf(name, binaryName)
+
+	if !force {
+		if isCurrent {
+			return fmt.Errorf("profile '%s' is the current profile for '%s': use --force to delete it anyway", name, binaryName)
+		}
+		if isDefault {
+			return fmt.Errorf("profile '%s' is the default profile for '%s': use --force to delete it anyway", name, binaryName)
+		}
+		if len(children) > 0 {
+			return fmt.Errorf("profile '%s' is extended by %v: use --force to delete it anyway", name, children)
+		}
+	}
+
+	if err := s.repo.Delete(name, binaryName); err != nil {
+		return fmt.Errorf("failed to delete profile: %w", err)
+	}
+
+	// Don't leave current.env or .default pointing to a missing profile
+	if isCurrent {
+		if err := s.repo.UnsetCurrent(binaryName); err != nil {
+			return fmt.Errorf("failed to unset current profile: %w", err)
+		}
+	}
+	if isDefault {
+		if err := s.repo.UnsetDefault(binaryName); err != nil {
+			return fmt.Errorf("failed to unset default profile: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RenameProfile renames a profile. The current and default markers and the
+// profiles extending it are updated to follow the new name, under the lock of
+// the binary. An existing profile named newName is replaced when overwrite is set.
+func (s *ProfileService) RenameProfile(name, newName, binaryName string, overwrite bool) error {
+	unlock, err := s.repo.Lock(binaryName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.prepareTarget(name, newName, binaryName, overwrite); err != nil {
+		return err
+	}
+
+	isCurrent := s.currentName(binaryName) == name
+	isDefault := s.defaultName(binaryName) == name
+	children := s.childrenOf(name, binaryName)
+
+	if err := s.repo.Rename(name, newName, binaryName, overwrite); err != nil {
+		switch err {
+		case domain.ErrProfileNotFound:
+			return fmt.Errorf("profile '%s' not found for binary '%s'", name, binaryName)
+		case domain.ErrProfileAlreadyExists:
+			return fmt.Errorf("profile '%s' for binary '%s' already exists", newName, binaryName)
+		}
+		return fmt.Errorf("failed to rename profile: %w", err)
+	}
+
+	if isCurrent {
+		if err := s.repo.SetCurrent(newName, binaryName); err != nil {
+			return fmt.Errorf("failed to update current profile: %w", err)
+		}
+	}
+	if isDefault {
+		if err := s.repo.SetDefault(newName, binaryName); err != nil {
+			return fmt.Errorf("failed to update default profile: %w", err)
+		}
+	}
+
+	for _, childName := range children {
+		child, err := s.repo.FindByName(childName, binaryName)
+		if err != nil {
+			return fmt.Errorf("failed to update profile '%s': %w", childName, err)
+		}
+		child.SetParent(newName)
+		if err := s.repo.Save(child); err != nil {
+			return fmt.Errorf("failed to update profile '%s': %w", childName, err)
+		}
+	}
+
+	return nil
+}
+
+// CopyProfile copies a profile under a new name. An existing profile named
+// newName is replaced when overwrite is set.
+func (s *ProfileService) CopyProfile(name, newName, binaryName string, overwrite bool) error {
+	unlock, err := s.repo.Lock(binaryName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.prepareTarget(name, newName, binaryName, overwrite); err != nil {
+		return err
+	}
+
+	if err := s.repo.Copy(name, newName, binaryName, overwrite); err != nil {
+		switch err {
+		case domain.ErrProfileNotFound:
+			return fmt.Errorf("profile '%s' not found for binary '%s'", name, binaryName)
+		case domain.ErrProfileAlreadyExists:
+			return fmt.Errorf("profile '%s' for binary '%s' already exists", newName, binaryName)
+		}
+		return fmt.Errorf("failed to copy profile: %w", err)
+	}
+
+	return nil
+}
+
+// EncryptProfile encrypts a profile file at rest. An empty name selects the
+// profile executions use.
+func (s *ProfileService) EncryptProfile(name, binaryName string) (string, error) {
+	name, err := s.profileNameOrActive(name, binaryName)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.repo.Encrypt(name, binaryName); err != nil {
+		switch err {
+		case domain.ErrProfileNotFound:
+			return "", fmt.Errorf("profile '%s' not found for binary '%s'", name, binaryName)
+		case domain.ErrProfileEncrypted:
+			return "", fmt.Errorf("profile '%s' for binary '%s' is already encrypted", name, binaryName)
+		}
+		return "", fmt.Errorf("failed to encrypt profile: %w", err)
+	}
+
+	return name, nil
+}
+
+// DecryptProfile stores an encrypted profile in plain text again. An empty
+// name selects the profile executions use.
+func (s *ProfileService) DecryptProfile(name, binaryName string) (string, error) {
+	name, err := s.profileNameOrActive(name, binaryName)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.repo.Decrypt(name, binaryName); err != nil {
+		switch err {
+		case domain.ErrProfileNotFound:
+			return "", fmt.Errorf("profile '%s' not found for binary '%s'", name, binaryName)
+		case domain.ErrProfileNotEncrypted:
+			return "", fmt.Errorf("profile '%s' for binary '%s' is not encrypted", name, binaryName)
+		}
+		return "", fmt.Errorf("failed to decrypt profile: %w", err)
+	}
+
+	return name, nil
+}
+
+// GetProfile returns a profile by name as stored, without inherited values.
+// An empty name selects the profile executions use.
+func (s *ProfileService) GetProfile(name, binaryName string) (*domain.Profile, error) {
+	name, err := s.profileNameOrActive(name, binaryName)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := s.repo.FindByName(name, binaryName)
+	if err != nil {
+		if err == domain.ErrProfileNotFound {
+			return nil, fmt.Errorf("profile '%s' not found for binary '%s'", name, binaryName)
+		}
+		return nil, fmt.Errorf("failed to find profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+// GetResolvedProfile returns a profile by name merged with the profiles it extends.
+// An empty name selects the profile executions use.
+func (s *ProfileService) GetResolvedProfile(name, binaryName string) (*domain.Profile, error) {
+	name, err := s.profileNameOrActive(name, binaryName)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := s.repo.Resolve(name, binaryName)
+	if err != nil {
+		if err == domain.ErrProfileNotFound {
+			return nil, fmt.Errorf("profile '%s' not found for binary '%s'", name, binaryName)
+		}
+		return nil, fmt.Errorf("failed to resolve profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+// SetVariable sets a variable in a profile and returns the profile name. The
+// value is stored as is: it is never expanded, so set, get and execution all
+// see the same value. The profile is locked from load to save, so concurrent
+// commands don't drop each other's variables.
+func (s *ProfileService) SetVariable(name, binaryName, key, value string) (string, error) {
+	if !domain.IsValidVariableName(key) {
+		return "", fmt.Errorf("invalid variable name '%s'", key)
+	}
+
+	unlock, err := s.repo.Lock(binaryName)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	profile, err := s.GetProfile(name, binaryName)
+	if err != nil {
+		return "", err
+	}
+
+	profile.AddLiteralVariable(key, value)
+	if err := s.repo.Save(profile); err != nil {
+		return "", fmt.Errorf("failed to save profile: %w", err)
+	}
+
+	return profile.Name(), nil
+}
+
+// GetVariable returns the value of a variable of a profile, including inherited
+// values. Values are returned as stored, before expansion.
+func (s *ProfileService) GetVariable(name, binaryName, key string) (string, error) {
+	profile, err := s.GetResolvedProfile(name, binaryName)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := profile.Environment()[key]
+	if !ok {
+		return "", fmt.Errorf("variable '%s' is not defined in profile '%s'", key, profile.Name())
+	}
+
+	return value, nil
+}
+
+// UnsetVariable removes a variable from a profile and returns the profile name
+func (s *ProfileService) UnsetVariable(name, binaryName, key string) (string, error) {
+	unlock, err := s.repo.Lock(binaryName)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	profile, err := s.GetProfile(name, binaryName)
+	if err != nil {
+		return "", err
+	}
+
+	if _, ok := profile.Environment()[key]; !ok {
+		return "", fmt.Errorf("variable '%s' is not defined in profile '%s'", key, profile.Name())
+	}
+
+	profile.RemoveEnvironmentVariable(key)
+	if err := s.repo.Save(profile); err != nil {
+		return "", fmt.Errorf("failed to save profile: %w", err)
+	}
+
+	return profile.Name(), nil
+}
+
+// ReadProfileContent returns the name and raw content of a profile.
+// An empty name selects the profile executions use.
+func (s *ProfileService) ReadProfileContent(name, binaryName string) (string, []byte, error) {
+	name, err := s.profileNameOrActive(name, binaryName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	content, err := s.repo.ReadContent(name, binaryName)
+	if err != nil {
+		if err == domain.ErrProfileNotFound {
+			return "", nil, fmt.Errorf("profile '%s' not found for binary '%s'", name, binaryName)
+		}
+		return "", nil, err
+	}
+
+	return name, content, nil
+}
+
+// WriteProfileContent validates and replaces the raw content of a profile.
+// Validation errors wrap domain.ErrInvalidProfile.
+func (s *ProfileService) WriteProfileContent(name, binaryName string, content []byte) error {
+	return s.repo.WriteContent(name, binaryName, content)
+}
+
+// SelectProfile returns the name of the profile executions of a binary use and
+// why it was selected: a WRAPPER_PROFILE_<BINARY> or WRAPPER_PROFILE variable, a
+// trusted .wrapper file, the current profile or the default profile
+func (s *ProfileService) SelectProfile(binaryName string) (string, string, error) {
+	name, source, err := s.selector.selectName(binaryName, "", "")
+	if err != nil {
+		if err == domain.ErrNoCurrentProfile {
+			return "", "", fmt.Errorf("no active profile for binary '%s': specify a profile name", binaryName)
+		}
+		return "", "", fmt.Errorf("failed to get active profile: %w", err)
+	}
+	return name, source, nil
+}
+
+// profileNameOrActive returns name, or the name of the profile executions use
+// when empty
+func (s *ProfileService) profileNameOrActive(name, binaryName string) (string, error) {
+	if name != "" {
+		return name, nil
+	}
+
+	name, _, err := s.SelectProfile(binaryName)
+	return name, err
+}
+
+// ProfileExists checks whether a profile exists for a binary
+func (s *ProfileService) ProfileExists(name, binaryName string) bool {
+	return s.repo.Exists(name, binaryName)
+}
+
+// prepareTarget validates the target of a rename or copy. The repository
+// replaces an existing target when overwrite is set, unless the source extends
+// it: the source would then lose its own parent.
+func (s *ProfileService) prepareTarget(name, newName, binaryName string, overwrite bool) error {
+	if _, err := domain.NewProfileName(newName); err != nil {
+		return err
+	}
+	if name == newName {
+		return fmt.Errorf("source and target profile are both '%s'", name)
+	}
+
+	if !overwrite {
+		return nil
+	}
+
+	for _, ancestor := range s.ancestorsOf(name, binaryName) {
+		if ancestor == newName {
+			return fmt.Errorf("profile '%s' extends '%s': it can't replace it", name, newName)
+		}
+	}
+
+	return nil
+}
+
+// ancestorsOf returns the names of the profiles a profile extends, directly
+// or not, as far as they can be read
+func (s *ProfileService) ancestorsOf(name, binaryName string) []string {
+	var ancestors []string
+	seen := map[string]bool{name: true}
+
+	for {
+		profile, err := s.repo.FindByName(name, binaryName)
+		if err != nil || profile.Parent() == "" || seen[profile.Parent()] {
+			return ancestors
+		}
+
+		name = profile.Parent()
+		seen[name] = true
+		ancestors = append(ancestors, name)
+	}
+}
+
+// currentName returns the name of the current profile, or "" if none is set.
+// It is read from the marker, so it is known even if the profile can't be read.
+func (s *ProfileService) currentName(binaryName string) string {
+	name, err := s.repo.CurrentName(binaryName)
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// defaultName returns the name of the default profile, or "" if none is set.
+// It is read from the marker, so it is known even if the profile can't be read.
+func (s *ProfileService) defaultName(binaryName string) string {
+	name, err := s.repo.DefaultName(binaryName)
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
+// childrenOf returns the names of the profiles directly extending a profile
+func (s *ProfileService) childrenOf(name, binaryName string) []string {
+	profiles, err := s.repo.List(binaryName)
+	if err != nil {
+		return nil
+	}
+
+	var children []string
+	for _, profile := range profiles {
+		if profile.Parent() == name {
+			children = append(children, profile.Name())
+		}
+	}
+	return children
+}
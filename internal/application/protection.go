@@ -0,0 +1,72 @@
+package application
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jycamier/wrapper/internal/domain"
+)
+
+// AssumeYesEnvVar skips the confirmation of protected profiles when set to a true value
+const AssumeYesEnvVar = "WRAPPER_YES"
+
+// ANSI codes of the protected profile banner
+const (
+	bannerStyle = "\033[1;97;41m"
+	bannerBold  = "\033[1m"
+	bannerReset = "\033[0m"
+)
+
+// confirmProtected requires the user to type the profile name before a
+// protected profile is used. Without a terminal, the execution is refused
+// unless the confirmation is given up front.
+func confirmProtected(profile *domain.Profile, binaryName string, args []string, assumeYes bool) error {
+	if !profile.IsProtected() || !requiresConfirmation(profile, binaryName, args) {
+		return nil
+	}
+
+	if assumeYes || domain.IsTrue(os.Getenv(AssumeYesEnvVar)) {
+		return nil
+	}
+
+	// Use the terminal directly: stdin may carry data for the binary
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("profile '%s' is protected: use --wrapper-yes or %s=1 to confirm in non-interactive runs",
+			profile.Name(), AssumeYesEnvVar)
+	}
+	defer tty.Close()
+
+	command := strings.Join(append([]string{binaryName}, args...), " ")
+	fmt.Fprintf(tty, "%s PROTECTED PROFILE %s %s%s%s → %s%s%s\n", bannerStyle, bannerReset,
+		bannerBold, binaryName, bannerReset, bannerBold, profile.Name(), bannerReset)
+	fmt.Fprintf(tty, "  command: %s\n", command)
+	fmt.Fprintf(tty, "Type the profile name to continue: ")
+
+	answer, _ := bufio.NewReader(tty).ReadString('\n')
+	if strings.TrimSpace(answer) != profile.Name() {
+		return fmt.Errorf("aborted: confirmation for protected profile '%s' did not match", profile.Name())
+	}
+
+	return nil
+}
+
+// requiresConfirmation checks the arguments against the confirm patterns of
+// the profile. Patterns may start with the binary name ("kubectl delete").
+func requiresConfirmation(profile *domain.Profile, binaryName string, args []string) bool {
+	patterns := profile.ConfirmPatterns()
+	if len(patterns) == 0 {
+		return true
+	}
+
+	command := append([]string{binaryName}, args...)
+	for _, pattern := range patterns {
+		if domain.MatchArguments(pattern, args) || domain.MatchArguments(pattern, command) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,55 @@
+//go:build !linux && !darwin
+
+package application
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+)
+
+// childStatus is how a child process terminated
+type childStatus struct {
+	state *os.ProcessState
+}
+
+// exitCode returns the exit code of the child
+func (s *childStatus) exitCode() int {
+	return s.state.ExitCode()
+}
+
+// success reports whether the child exited with status 0
+func (s *childStatus) success() bool {
+	return s.state.Success()
+}
+
+// runChild starts a command and waits for it to terminate. Interrupts are
+// already delivered to the child by the console, the wrapper only ignores them.
+func runChild(cmd *exec.Cmd) (*childStatus, error) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return &childStatus{state: exitErr.ProcessState}, nil
+		}
+		return nil, err
+	}
+
+	return &childStatus{state: cmd.ProcessState}, nil
+}
+
+// exitStatus returns the exit code of a process
+func exitStatus(state *os.ProcessState) int {
+	return state.ExitCode()
+}
+
+// exitLikeChild terminates the wrapper with the exit code of the child
+func exitLikeChild(status *childStatus) {
+	os.Exit(status.exitCode())
+}
@@ -0,0 +1,169 @@
+//go:build linux || darwin
+
+package application
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// keptSignals are not relayed to the child: they can't be caught, report
+// faults or state changes of the wrapper itself, or are used by the Go runtime.
+// Job control signals keep their default action, so that the wrapper stops
+// and resumes with the other processes of its job.
+var keptSignals = map[syscall.Signal]bool{
+	syscall.SIGKILL: true,
+	syscall.SIGSTOP: true,
+	syscall.SIGTSTP: true,
+	syscall.SIGTTIN: true,
+	syscall.SIGTTOU: true,
+	syscall.SIGCONT: true,
+	syscall.SIGCHLD: true,
+	syscall.SIGURG:  true,
+	syscall.SIGPROF: true,
+	syscall.SIGPIPE: true,
+	syscall.SIGSEGV: true,
+	syscall.SIGBUS:  true,
+	syscall.SIGFPE:  true,
+	syscall.SIGILL:  true,
+	syscall.SIGTRAP: true,
+	syscall.SIGSYS:  true,
+}
+
+// terminalSignals are sent by the terminal to its whole foreground process
+// group, so the child already received them when the wrapper is in it
+var terminalSignals = map[os.Signal]bool{
+	syscall.SIGINT:   true,
+	syscall.SIGQUIT:  true,
+	syscall.SIGWINCH: true,
+}
+
+// forwardedSignals returns the standard signals relayed to the child
+func forwardedSignals() []os.Signal {
+	var sigs []os.Signal
+	for sig := syscall.Signal(1); sig < 32; sig++ {
+		if !keptSignals[sig] {
+			sigs = append(sigs, sig)
+		}
+	}
+	return sigs
+}
+
+// childStatus is how a child process terminated
+type childStatus struct {
+	status syscall.WaitStatus
+}
+
+// exitCode returns the exit code of the child as reported by shells: 128+N
+// when it was killed by signal N
+func (s *childStatus) exitCode() int {
+	if s.status.Signaled() {
+		return 128 + int(s.status.Signal())
+	}
+	return s.status.ExitStatus()
+}
+
+// success reports whether the child exited with status 0
+func (s *childStatus) success() bool {
+	return s.status.Exited() && s.status.ExitStatus() == 0
+}
+
+// runChild starts a command and waits for it to terminate, relaying the
+// signals received by the wrapper to it. The child stays in the process group
+// of the wrapper: the terminal and the shell handle it as part of the same
+// job, so pipelines, job control and terminal signals work unchanged.
+func runChild(cmd *exec.Cmd) (*childStatus, error) {
+	// Signals received while the child starts are relayed once it runs
+	sigs := make(chan os.Signal, 16)
+	signal.Notify(sigs, forwardedSignals()...)
+	defer signal.Stop(sigs)
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go relaySignals(cmd.Process, sigs, done)
+
+	err := cmd.Wait()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return &childStatus{status: exitErr.Sys().(syscall.WaitStatus)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &childStatus{status: cmd.ProcessState.Sys().(syscall.WaitStatus)}, nil
+}
+
+// relaySignals sends the signals received by the wrapper to the child until
+// done is closed, except the ones the terminal already delivered to it
+func relaySignals(process *os.Process, sigs <-chan os.Signal, done <-chan struct{}) {
+	for {
+		select {
+		case sig := <-sigs:
+			if terminalSignals[sig] && inForegroundProcessGroup() {
+				continue
+			}
+			_ = process.Signal(sig)
+		case <-done:
+			return
+		}
+	}
+}
+
+// inForegroundProcessGroup checks whether the wrapper belongs to the
+// foreground process group of its controlling terminal
+func inForegroundProcessGroup() bool {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return false
+	}
+	defer tty.Close()
+
+	var pgrp int32
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, tty.Fd(), uintptr(syscall.TIOCGPGRP), uintptr(unsafe.Pointer(&pgrp)))
+	if errno != 0 {
+		return false
+	}
+
+	return int(pgrp) == syscall.Getpgrp()
+}
+
+// exitStatus returns the exit code of a process as reported by shells:
+// 128+N when it was killed by signal N
+func exitStatus(state *os.ProcessState) int {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok {
+		return (&childStatus{status: status}).exitCode()
+	}
+	return state.ExitCode()
+}
+
+// exitLikeChild terminates the wrapper the same way the child terminated: with
+// the same exit code, or by raising the signal that killed it so the shell
+// reports 128+N just as if the binary had been run directly
+func exitLikeChild(status *childStatus) {
+	if !status.status.Signaled() {
+		os.Exit(status.exitCode())
+	}
+
+	sig := status.status.Signal()
+
+	// The Go runtime only dies from these signals when they are not handled;
+	// other signals are reported through the equivalent 128+N exit code
+	switch sig {
+	case syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGKILL:
+		signal.Reset(sig)
+		_ = syscall.Kill(os.Getpid(), sig)
+
+		// Give the signal time to be delivered
+		time.Sleep(time.Second)
+	}
+
+	os.Exit(128 + int(sig))
+}
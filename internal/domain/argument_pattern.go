@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchArguments reports whether args match a pattern such as "delete" or
+// "kv delete". The words of the pattern must appear in args in the same order,
+// not necessarily next to each other so that interleaved flags
+// ("kubectl -n prod delete pod x") still match. Words may use glob syntax.
+func MatchArguments(pattern string, args []string) bool {
+	words := strings.Fields(pattern)
+	if len(words) == 0 {
+		return false
+	}
+
+	next := 0
+	for _, arg := range args {
+		if matched, _ := path.Match(words[next], arg); matched {
+			next++
+			if next == len(words) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,122 @@
+package domain
+
+import "path/filepath"
+
+// DirectiveKind identifies how a directive changes a variable
+type DirectiveKind int
+
+const (
+	// DirectiveUnset removes a variable, e.g. "unset AWS_PROFILE"
+	DirectiveUnset DirectiveKind = iota
+	// DirectiveAppend adds a list item at the end of a variable, e.g. "PATH+=/opt/bin"
+	DirectiveAppend
+	// DirectivePrepend adds a list item at the start of a variable, e.g. "PATH^=/opt/bin"
+	DirectivePrepend
+)
+
+// Directive changes a variable relative to its inherited value instead of
+// replacing it
+type Directive struct {
+	kind    DirectiveKind
+	key     string
+	value   string
+	literal bool
+}
+
+// NewDirective creates a new Directive. A literal value is used as is, others
+// are expanded. The value is ignored by DirectiveUnset.
+func NewDirective(kind DirectiveKind, key, value string, literal bool) Directive {
+	if kind == DirectiveUnset {
+		value, literal = "", false
+	}
+	return Directive{kind: kind, key: key, value: value, literal: literal}
+}
+
+// Kind returns how the directive changes the variable
+func (d Directive) Kind() DirectiveKind {
+	return d.kind
+}
+
+// Key returns the name of the variable
+func (d Directive) Key() string {
+	return d.key
+}
+
+// Value returns the list item added by the directive
+func (d Directive) Value() string {
+	return d.value
+}
+
+// IsLiteral reports whether the value is used as is rather than expanded
+func (d Directive) IsLiteral() bool {
+	return d.literal
+}
+
+// Directives returns the directives of the profile, in order
+func (p *Profile) Directives() []Directive {
+	// Return a copy to prevent external modification
+	return append([]Directive(nil), p.directives...)
+}
+
+// SetDirectives replaces the directives of the profile
+func (p *Profile) SetDirectives(directives []Directive) {
+	p.directives = append([]Directive(nil), directives...)
+}
+
+// ApplyDirectives applies directives to env, a complete environment: the
+// process environment with the profile variables. This is how "PATH^=/opt/bin"
+// extends the PATH of the process. Items are joined with the path list
+// separator of the platform and expanded like profile values.
+func ApplyDirectives(env map[string]string, directives []Directive) error {
+	lookup := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	for _, d := range directives {
+		// Options never reach the environment
+		if IsOptionKey(d.key) {
+			continue
+		}
+
+		if d.kind == DirectiveUnset {
+			delete(env, d.key)
+			continue
+		}
+
+		value := d.value
+		if !d.literal {
+			expanded, err := ExpandEnvironment(map[string]string{d.key: d.value}, lookup)
+			if err != nil {
+				return err
+			}
+			value = expanded[d.key]
+		}
+
+		if base := env[d.key]; base != "" {
+			switch {
+			case value == "":
+				value = base
+			case d.kind == DirectiveAppend:
+				value = base + string(filepath.ListSeparator) + value
+			default:
+				value = value + string(filepath.ListSeparator) + base
+			}
+		}
+
+		env[d.key] = value
+	}
+
+	return nil
+}
+
+// withoutKey returns the directives that don't change key
+func withoutKey(directives []Directive, key string) []Directive {
+	kept := directives[:0]
+	for _, d := range directives {
+		if d.key != key {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,41 @@
+package domain
+
+import "errors"
+
+const (
+	// PassphraseEnvVar holds the passphrase of encrypted profiles
+	PassphraseEnvVar = "WRAPPER_PASSPHRASE"
+	// PassphraseCommandEnvVar holds a command printing the passphrase, e.g. a
+	// password manager or keychain lookup
+	PassphraseCommandEnvVar = "WRAPPER_PASSPHRASE_COMMAND"
+)
+
+var (
+	// ErrEncryptionKeyUnavailable is returned when no passphrase or key is configured
+	ErrEncryptionKeyUnavailable = errors.New("no encryption key available")
+	// ErrDecryptionFailed is returned when encrypted data cannot be authenticated
+	ErrDecryptionFailed = errors.New("decryption failed: wrong passphrase or corrupted data")
+	// ErrProfileEncrypted is returned when encrypting an already encrypted profile
+	ErrProfileEncrypted = errors.New("profile is already encrypted")
+	// ErrProfileNotEncrypted is returned when decrypting a plain text profile
+	ErrProfileNotEncrypted = errors.New("profile is not encrypted")
+)
+
+// ProfileCipher encrypts profile files at rest
+type ProfileCipher interface {
+	// Encrypt encrypts the content of a profile file
+	Encrypt(plaintext []byte) ([]byte, error)
+
+	// Decrypt decrypts the content of an encrypted profile file
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// IsEncrypted reports whether the profile is stored encrypted
+func (p *Profile) IsEncrypted() bool {
+	return p.encrypted
+}
+
+// SetEncrypted records whether the profile is stored encrypted
+func (p *Profile) SetEncrypted(encrypted bool) {
+	p.encrypted = encrypted
+}
@@ -0,0 +1,180 @@
+package domain
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// EnvMode selects how profile variables combine with the process environment
+type EnvMode string
+
+const (
+	// EnvModeOverride makes profile variables replace the process ones
+	EnvModeOverride EnvMode = "override"
+	// EnvModeFillMissing only sets profile variables missing from the process
+	// environment
+	EnvModeFillMissing EnvMode = "fill-missing"
+)
+
+// BuildEnvironment builds the environment of a binary from layers of
+// increasing precedence:
+//
+//  1. environ, the process environment as KEY=VALUE entries, restricted to
+//     the profile allowlist when the profile requires a clean environment.
+//     The passphrase of encrypted profiles is never passed on.
+//  2. the profile variables, which replace process variables or only fill
+//     the missing ones depending on the profile EnvMode. Unless secrets is
+//     nil, secret references are resolved as soon as a value is expanded, so
+//     variables referencing them get the secret.
+//  3. the profile directives (unset, append, prepend)
+//  4. overrides, one-shot values given for a single execution, whose secret
+//     references are resolved as well
+//
+// Each key appears once in the result, sorted by key.
+func BuildEnvironment(environ []string, profile *Profile, overrides map[string]string, secrets *SecretResolver) ([]string, error) {
+	mode, err := profile.EnvMode()
+	if err != nil {
+		return nil, err
+	}
+
+	env := EnvironMap(environ)
+	delete(env, PassphraseEnvVar)
+	delete(env, PassphraseCommandEnvVar)
+
+	if profile.IsCleanEnv() {
+		allowlist := profile.EnvAllowlist()
+		for key := range env {
+			if !matchAny(allowlist, key) {
+				delete(env, key)
+			}
+		}
+	}
+
+	variables := profile.Variables()
+	for key, value := range variables {
+		// Escaped so that expansion leaves literal values unchanged
+		if profile.IsLiteral(key) {
+			variables[key] = strings.ReplaceAll(value, "$", "$$")
+		}
+	}
+	if mode == EnvModeFillMissing {
+		// References to a skipped key then see the process value, like the binary
+		for key := range variables {
+			if _, ok := env[key]; ok {
+				delete(variables, key)
+			}
+		}
+	}
+
+	// Expand variable references against the profile and the process environment
+	lookup := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+	expanded, err := ExpandEnvironment(variables, lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	if secrets != nil {
+		// All references are checked before any provider is called
+		if err := secrets.CheckSecrets(expanded); err != nil {
+			return nil, err
+		}
+		if err := secrets.CheckSecrets(overrides); err != nil {
+			return nil, err
+		}
+
+		// Keys replaced by an override are only resolved when referenced
+		keys := make([]string, 0, len(variables))
+		for key := range variables {
+			if _, ok := overrides[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		// A secret is resolved once its key is expanded: references to the key
+		// get the secret, which is used as is even if it contains '$'
+		scope := profile.BinaryName() + "/" + profile.Name()
+		expanded, err = expandKeys(variables, keys, lookup, func(key, value string) (string, error) {
+			return secrets.ResolveValue(scope, key, value)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// Overrides are never expanded, only their references are resolved
+		overrideKeys := make([]string, 0, len(overrides))
+		for key := range overrides {
+			overrideKeys = append(overrideKeys, key)
+		}
+		sort.Strings(overrideKeys)
+
+		resolved := make(map[string]string, len(overrides))
+		for _, key := range overrideKeys {
+			value, err := secrets.ResolveValue(scope, key, overrides[key])
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = value
+		}
+		overrides = resolved
+	}
+
+	for key, value := range expanded {
+		env[key] = value
+	}
+
+	if err := ApplyDirectives(env, profile.Directives()); err != nil {
+		return nil, err
+	}
+
+	for key, value := range overrides {
+		env[key] = value
+	}
+
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, fmt.Sprintf("%s=%s", key, env[key]))
+	}
+	return result, nil
+}
+
+// EnvironMap turns KEY=VALUE entries into a map. When a key appears several
+// times, its last entry is used.
+func EnvironMap(environ []string) map[string]string {
+	env := make(map[string]string, len(environ))
+	for _, entry := range environ {
+		if entry == "" {
+			continue
+		}
+		// Look for '=' after the first character: Windows keeps per-drive
+		// directories in keys starting with '=' (=C:=C:\)
+		i := strings.IndexByte(entry[1:], '=') + 1
+		if i == 0 {
+			continue
+		}
+		env[entry[:i]] = entry[i+1:]
+	}
+	return env
+}
+
+// matchAny reports whether a variable name matches one of the patterns. Names
+// are compared case-insensitively since Windows ignores their case.
+func matchAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(strings.ToUpper(pattern), strings.ToUpper(key)); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,254 @@
+package domain
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestBuildEnvironment(t *testing.T) {
+	sep := string(filepath.ListSeparator)
+
+	tests := []struct {
+		name       string
+		environ    []string
+		variables  map[string]string
+		literals   map[string]string
+		directives []Directive
+		overrides  map[string]string
+		want       []string
+		wantErr    error
+	}{
+		{
+			name:      "profile variables replace process variables",
+			environ:   []string{"A=process", "B=process"},
+			variables: map[string]string{"A": "profile", "C": "profile"},
+			want:      []string{"A=profile", "B=process", "C=profile"},
+		},
+		{
+			name:      "fill-missing keeps process variables",
+			environ:   []string{"A=process"},
+			variables: map[string]string{OptionEnvMode: "fill-missing", "A": "profile", "C": "profile"},
+			want:      []string{"A=process", "C=profile"},
+		},
+		{
+			name:      "fill-missing references see the process value",
+			environ:   []string{"HOST=process"},
+			variables: map[string]string{OptionEnvMode: "fill-missing", "HOST": "profile", "URL": "https://${HOST}"},
+			want:      []string{"HOST=process", "URL=https://process"},
+		},
+		{
+			name:      "invalid env mode",
+			variables: map[string]string{OptionEnvMode: "merge"},
+			wantErr:   ErrInvalidOption,
+		},
+		{
+			name:    "duplicate keys in environ keep the last entry",
+			environ: []string{"A=first", "B=kept", "A=last", "", "=C:=C:\\"},
+			want:    []string{"=C:=C:\\", "A=last", "B=kept"},
+		},
+		{
+			name:      "references expand against profile then process",
+			environ:   []string{"USER=me", "REGION=process"},
+			variables: map[string]string{"REGION": "eu", "PREFIX": "${USER}-${REGION}"},
+			want:      []string{"PREFIX=me-eu", "REGION=eu", "USER=me"},
+		},
+		{
+			name:      "literal values are not expanded",
+			variables: map[string]string{"A": "$UNDEFINED"},
+			literals:  map[string]string{"PASS": "pa$word", "A": "pa$$word"},
+			want:      []string{"A=pa$$word", "PASS=pa$word"},
+		},
+		{
+			name:      "undefined reference",
+			variables: map[string]string{"A": "$UNDEFINED"},
+			wantErr:   ErrUndefinedVariable,
+		},
+		{
+			name:    "directives apply in order",
+			environ: []string{"PATH=/bin", "OLD=process"},
+			directives: []Directive{
+				NewDirective(DirectivePrepend, "PATH", "/a", false),
+				NewDirective(DirectiveAppend, "PATH", "/b", false),
+				NewDirective(DirectivePrepend, "PATH", "/c", false),
+				NewDirective(DirectiveUnset, "OLD", "", false),
+				NewDirective(DirectiveAppend, "OLD", "new", false),
+			},
+			want: []string{"OLD=new", "PATH=/c" + sep + "/a" + sep + "/bin" + sep + "/b"},
+		},
+		{
+			name:      "directives see expanded profile variables",
+			environ:   []string{"PATH=/bin"},
+			variables: map[string]string{"ROOT": "/opt/${TOOL}", "TOOL": "tool"},
+			directives: []Directive{
+				NewDirective(DirectivePrepend, "PATH", "${ROOT}/bin", false),
+				NewDirective(DirectiveAppend, "PATH", "$ROOT", true),
+			},
+			want: []string{"PATH=/opt/tool/bin" + sep + "/bin" + sep + "$ROOT", "ROOT=/opt/tool", "TOOL=tool"},
+		},
+		{
+			name:       "overrides win over process, profile and directives",
+			environ:    []string{"A=process", "PATH=/bin"},
+			variables:  map[string]string{"A": "profile", "B": "profile"},
+			directives: []Directive{NewDirective(DirectiveAppend, "PATH", "/opt/bin", false)},
+			overrides:  map[string]string{"A": "override", "PATH": "/override", "C": "$NOT_EXPANDED"},
+			want:       []string{"A=override", "B=profile", "C=$NOT_EXPANDED", "PATH=/override"},
+		},
+		{
+			name:      "clean environment keeps allowlisted variables",
+			environ:   []string{"HOME=/home/me", "SECRET=leak", "CI_JOB=1", "Path=/bin"},
+			variables: map[string]string{OptionCleanEnv: "true", OptionEnvAllowlist: "ci_*", "A": "profile"},
+			want:      []string{"A=profile", "CI_JOB=1", "HOME=/home/me", "Path=/bin"},
+		},
+		{
+			name:      "options and the passphrase never reach the environment",
+			environ:   []string{PassphraseEnvVar + "=secret", PassphraseCommandEnvVar + "=pass show wrapper", "A=process"},
+			variables: map[string]string{OptionProtected: "true", OptionArgsAppend: "--verbose"},
+			want:      []string{"A=process"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := newTestProfile(t, tt.variables)
+			for key, value := range tt.literals {
+				profile.AddLiteralVariable(key, value)
+			}
+			profile.SetDirectives(tt.directives)
+
+			got, err := BuildEnvironment(tt.environ, profile, tt.overrides, nil)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("BuildEnvironment() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BuildEnvironment() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildEnvironment() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildEnvironmentSecrets(t *testing.T) {
+	tests := []struct {
+		name        string
+		environ     []string
+		variables   map[string]string
+		overrides   map[string]string
+		want        []string
+		wantFetched []string
+		wantErr     error
+	}{
+		{
+			name:        "references to a secret get the secret",
+			variables:   map[string]string{"DB_PASS": "ref+test://db", "DB_URL": "postgres://app:${DB_PASS}@db"},
+			want:        []string{"DB_PASS=secret-db", "DB_URL=postgres://app:secret-db@db"},
+			wantFetched: []string{"db"},
+		},
+		{
+			name:        "references are expanded before they are resolved",
+			environ:     []string{"NAME=vault"},
+			variables:   map[string]string{"TOKEN": "ref+test://${NAME}"},
+			want:        []string{"NAME=vault", "TOKEN=secret-vault"},
+			wantFetched: []string{"vault"},
+		},
+		{
+			name:        "secrets are used as is",
+			variables:   map[string]string{"TOKEN": "ref+test://$$dollar"},
+			want:        []string{"TOKEN=secret-$dollar"},
+			wantFetched: []string{"$dollar"},
+		},
+		{
+			name:        "override references are resolved",
+			variables:   map[string]string{"TOKEN": "ref+test://profile"},
+			overrides:   map[string]string{"TOKEN": "ref+test://override"},
+			want:        []string{"TOKEN=secret-override"},
+			wantFetched: []string{"override"},
+		},
+		{
+			name:        "overridden keys are resolved only when referenced",
+			variables:   map[string]string{"A": "ref+test://a", "B": "ref+test://b", "C": "${B}"},
+			overrides:   map[string]string{"A": "plain", "B": "plain"},
+			want:        []string{"A=plain", "B=plain", "C=secret-b"},
+			wantFetched: []string{"b"},
+		},
+		{
+			name:        "fill-missing doesn't resolve skipped keys",
+			environ:     []string{"TOKEN=process"},
+			variables:   map[string]string{OptionEnvMode: "fill-missing", "TOKEN": "ref+test://token", "OTHER": "ref+test://other"},
+			want:        []string{"OTHER=secret-other", "TOKEN=process"},
+			wantFetched: []string{"other"},
+		},
+		{
+			name:      "references are checked before any is resolved",
+			variables: map[string]string{"A": "ref+test://a", "B": "ref+unknown://b"},
+			wantErr:   ErrUnknownSecretProvider,
+		},
+		{
+			name:      "override references are checked before any is resolved",
+			variables: map[string]string{"A": "ref+test://a"},
+			overrides: map[string]string{"B": "ref+test://"},
+			wantErr:   ErrInvalidSecretReference,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &fakeSecretProvider{}
+			secrets := NewSecretResolver(nil)
+			secrets.Register("test", provider)
+
+			got, err := BuildEnvironment(tt.environ, newTestProfile(t, tt.variables), tt.overrides, secrets)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("BuildEnvironment() error = %v, want %v", err, tt.wantErr)
+				}
+				if len(provider.fetched) > 0 {
+					t.Errorf("fetched %q before failing", provider.fetched)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BuildEnvironment() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildEnvironment() = %q, want %q", got, tt.want)
+			}
+
+			sort.Strings(provider.fetched)
+			if !reflect.DeepEqual(provider.fetched, tt.wantFetched) {
+				t.Errorf("fetched %q, want %q", provider.fetched, tt.wantFetched)
+			}
+		})
+	}
+}
+
+// newTestProfile creates a profile holding variables, which may include options
+func newTestProfile(t *testing.T, variables map[string]string) *Profile {
+	t.Helper()
+
+	profile, err := NewProfile("test", "tool", nil)
+	if err != nil {
+		t.Fatalf("NewProfile() error = %v", err)
+	}
+	for key, value := range variables {
+		profile.AddEnvironmentVariable(key, value)
+	}
+	return profile
+}
+
+// fakeSecretProvider returns "secret-<location>" and records the locations it fetched
+type fakeSecretProvider struct {
+	fetched []string
+}
+
+func (p *fakeSecretProvider) Fetch(location string) (string, error) {
+	p.fetched = append(p.fetched, location)
+	return "secret-" + location, nil
+}
@@ -0,0 +1,38 @@
+package domain
+
+// HookStage identifies when a hook runs relative to the real binary
+type HookStage string
+
+const (
+	// HookStagePre hooks run before the binary and can abort its execution
+	HookStagePre HookStage = "pre"
+	// HookStagePost hooks run after the binary has exited
+	HookStagePost HookStage = "post"
+)
+
+// Hook is an executable run before or after the real binary
+type Hook struct {
+	path  string
+	stage HookStage
+}
+
+// NewHook creates a new Hook
+func NewHook(path string, stage HookStage) *Hook {
+	return &Hook{path: path, stage: stage}
+}
+
+// Path returns the path of the hook executable
+func (h *Hook) Path() string {
+	return h.path
+}
+
+// Stage returns when the hook runs
+func (h *Hook) Stage() HookStage {
+	return h.stage
+}
+
+// HookRepository defines the interface for locating hooks
+type HookRepository interface {
+	// FindHooks returns the hooks of a stage for a binary and profile, in execution order
+	FindHooks(binaryName, profileName string, stage HookStage) ([]*Hook, error)
+}
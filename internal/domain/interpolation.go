@@ -0,0 +1,239 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrUndefinedVariable is returned when a value references a variable that is not defined
+	ErrUndefinedVariable = errors.New("undefined variable")
+	// ErrInterpolationCycle is returned when variables reference each other in a loop
+	ErrInterpolationCycle = errors.New("variable interpolation cycle")
+	// ErrInvalidReference is returned when a ${...} reference is malformed
+	ErrInvalidReference = errors.New("invalid variable reference")
+)
+
+// LookupFunc looks up a variable outside of the profile (e.g. the process environment)
+type LookupFunc func(name string) (string, bool)
+
+// finishFunc turns the expanded value of a key into its final value, e.g. by
+// resolving a secret reference
+type finishFunc func(key, value string) (string, error)
+
+// ExpandEnvironment expands $VAR, ${VAR} and ${VAR:-default} references in the
+// values of env. References are resolved against the other keys of env first,
+// then against lookup. A key referencing itself (PATH=$PATH:/opt/bin) is resolved
+// against lookup. "$$" produces a literal "$".
+func ExpandEnvironment(env map[string]string, lookup LookupFunc) (map[string]string, error) {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return expandKeys(env, keys, lookup, nil)
+}
+
+// expandKeys expands the given keys of env, in order, along with the keys
+// they reference. Each expanded value goes through finish, when not nil, and
+// references to the key see its final value.
+func expandKeys(env map[string]string, keys []string, lookup LookupFunc, finish finishFunc) (map[string]string, error) {
+	e := &expander{
+		env:      env,
+		lookup:   lookup,
+		finish:   finish,
+		expanded: make(map[string]string, len(env)),
+		visiting: make(map[string]bool),
+	}
+
+	result := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value, err := e.expandKey(key)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+
+	return result, nil
+}
+
+// expander holds the state of a single expandKeys call
+type expander struct {
+	env      map[string]string
+	lookup   LookupFunc
+	finish   finishFunc
+	expanded map[string]string
+	visiting map[string]bool
+	stack    []string
+}
+
+// expandKey expands the value of a profile key, detecting cycles
+func (e *expander) expandKey(key string) (string, error) {
+	if value, ok := e.expanded[key]; ok {
+		return value, nil
+	}
+
+	if e.visiting[key] {
+		path := append(append([]string{}, e.stack[indexOf(e.stack, key):]...), key)
+		return "", fmt.Errorf("%w: %s", ErrInterpolationCycle, strings.Join(path, " -> "))
+	}
+
+	e.visiting[key] = true
+	e.stack = append(e.stack, key)
+
+	value, err := e.expandValue(key, e.env[key])
+
+	e.stack = e.stack[:len(e.stack)-1]
+	delete(e.visiting, key)
+
+	if err == nil && e.finish != nil {
+		value, err = e.finish(key, value)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	e.expanded[key] = value
+	return value, nil
+}
+
+// resolve returns the value of a referenced variable
+func (e *expander) resolve(owner, name string) (string, bool, error) {
+	if _, ok := e.env[name]; ok && name != owner {
+		value, err := e.expandKey(name)
+		return value, true, err
+	}
+
+	if e.lookup != nil {
+		if value, ok := e.lookup(name); ok {
+			return value, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+// expandValue expands all references in a value belonging to the owner key
+func (e *expander) expandValue(owner, value string) (string, error) {
+	var b strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c != '$' || i+1 >= len(value) {
+			b.WriteByte(c)
+			continue
+		}
+
+		next := value[i+1]
+		switch {
+		case next == '$':
+			// Escaped dollar sign
+			b.WriteByte('$')
+			i++
+
+		case next == '{':
+			end := matchingBrace(value, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("%w in '%s': unterminated '${'", ErrInvalidReference, owner)
+			}
+
+			expr := value[i+2 : end]
+			name, fallback, hasFallback := strings.Cut(expr, ":-")
+			if !IsValidVariableName(name) {
+				return "", fmt.Errorf("%w in '%s': '${%s}'", ErrInvalidReference, owner, expr)
+			}
+
+			resolved, ok, err := e.resolve(owner, name)
+			if err != nil {
+				return "", err
+			}
+
+			if !ok || (hasFallback && resolved == "") {
+				if !hasFallback {
+					return "", fmt.Errorf("%w '%s' referenced by '%s'", ErrUndefinedVariable, name, owner)
+				}
+				resolved, err = e.expandValue(owner, fallback)
+				if err != nil {
+					return "", err
+				}
+			}
+
+			b.WriteString(resolved)
+			i = end
+
+		case isNameStart(next):
+			end := i + 1
+			for end < len(value) && isNameChar(value[end]) {
+				end++
+			}
+
+			name := value[i+1 : end]
+			resolved, ok, err := e.resolve(owner, name)
+			if err != nil {
+				return "", err
+			}
+			if !ok {
+				return "", fmt.Errorf("%w '%s' referenced by '%s'", ErrUndefinedVariable, name, owner)
+			}
+
+			b.WriteString(resolved)
+			i = end - 1
+
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// matchingBrace returns the index of the "}" closing a "${" opened before start
+func matchingBrace(value string, start int) int {
+	depth := 1
+	for i := start; i < len(value); i++ {
+		switch value[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// IsValidVariableName checks whether name is a valid environment variable name
+func IsValidVariableName(name string) bool {
+	if name == "" || !isNameStart(name[0]) {
+		return false
+	}
+	for i := 1; i < len(name); i++ {
+		if !isNameChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return 0
+}
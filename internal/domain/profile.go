@@ -9,13 +9,18 @@ import (
 type Profile struct {
 	name        string
 	binaryName  string
+	parent      string
 	environment map[string]string
+	literals    map[string]bool
+	directives  []Directive
+	encrypted   bool
+	insecure    bool
 }
 
 // NewProfile creates a new profile
 func NewProfile(name, binaryName string, env map[string]string) (*Profile, error) {
-	if name == "" {
-		return nil, errors.New("profile name cannot be empty")
+	if _, err := NewProfileName(name); err != nil {
+		return nil, err
 	}
 	if binaryName == "" {
 		return nil, errors.New("binary name cannot be empty")
@@ -29,6 +34,7 @@ func NewProfile(name, binaryName string, env map[string]string) (*Profile, error
 		name:        name,
 		binaryName:  binaryName,
 		environment: env,
+		literals:    make(map[string]bool),
 	}, nil
 }
 
@@ -42,6 +48,16 @@ func (p *Profile) BinaryName() string {
 	return p.binaryName
 }
 
+// Parent returns the name of the profile this profile extends, if any
+func (p *Profile) Parent() string {
+	return p.parent
+}
+
+// SetParent sets the name of the profile this profile extends
+func (p *Profile) SetParent(parent string) {
+	p.parent = parent
+}
+
 // Environment returns the environment variables
 func (p *Profile) Environment() map[string]string {
 	// Return a copy to prevent external modification
@@ -52,17 +68,39 @@ func (p *Profile) Environment() map[string]string {
 	return env
 }
 
-// SetEnvironment updates the environment variables
+// SetEnvironment updates the environment variables, all of them expanded
 func (p *Profile) SetEnvironment(env map[string]string) {
 	p.environment = make(map[string]string, len(env))
 	for k, v := range env {
 		p.environment[k] = v
 	}
+	p.literals = make(map[string]bool)
 }
 
-// AddEnvironmentVariable adds or updates a single environment variable
+// AddEnvironmentVariable adds or updates a single environment variable. Its
+// value is expanded at execution time.
 func (p *Profile) AddEnvironmentVariable(key, value string) {
 	p.environment[key] = value
+	delete(p.literals, key)
+}
+
+// AddLiteralVariable adds or updates a single environment variable whose
+// value is used as is, never expanded
+func (p *Profile) AddLiteralVariable(key, value string) {
+	p.environment[key] = value
+	p.literals[key] = true
+}
+
+// IsLiteral reports whether the value of a variable is used as is, e.g. a
+// single-quoted value, rather than expanded
+func (p *Profile) IsLiteral(key string) bool {
+	return p.literals[key]
+}
+
+// RemoveEnvironmentVariable removes a single environment variable
+func (p *Profile) RemoveEnvironmentVariable(key string) {
+	delete(p.environment, key)
+	delete(p.literals, key)
 }
 
 // String returns a string representation of the profile
@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidArguments is returned when an argument list cannot be split
+	ErrInvalidArguments = errors.New("invalid argument list")
+)
+
+// Arguments returns the command line passed to the binary: the profile
+// arguments are injected around the given ones. Appended arguments are placed
+// before a "--" separator so they are still parsed as flags, and the arguments
+// of a WRAPPER_ARGS_AFTER_<SUBCOMMAND> option follow the first non-flag
+// argument having such an option.
+func (p *Profile) Arguments(args []string) ([]string, error) {
+	prepend, err := p.argumentsOption(OptionArgsPrepend)
+	if err != nil {
+		return nil, err
+	}
+	appended, err := p.argumentsOption(OptionArgsAppend)
+	if err != nil {
+		return nil, err
+	}
+
+	end := len(args)
+	for i, arg := range args {
+		if arg == "--" {
+			end = i
+			break
+		}
+	}
+
+	// Position of the subcommand arguments, none by default
+	at := -1
+	var after []string
+	for i, arg := range args[:end] {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		key := OptionArgsAfterPrefix + envVarSuffix(arg)
+		if _, ok := p.environment[key]; !ok {
+			continue
+		}
+		if after, err = p.argumentsOption(key); err != nil {
+			return nil, err
+		}
+		at = i
+		break
+	}
+
+	result := make([]string, 0, len(prepend)+len(args)+len(after)+len(appended))
+	result = append(result, prepend...)
+	result = append(result, args[:at+1]...)
+	result = append(result, after...)
+	result = append(result, args[at+1:end]...)
+	result = append(result, appended...)
+	result = append(result, args[end:]...)
+	return result, nil
+}
+
+// argumentsOption splits an option holding an argument list
+func (p *Profile) argumentsOption(key string) ([]string, error) {
+	args, err := SplitArguments(p.environment[key])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	return args, nil
+}
+
+// SplitArguments splits a value into arguments like a POSIX shell does,
+// without any expansion: words are separated by whitespace, single quotes
+// keep their content literally, double quotes and backslashes escape the
+// following character.
+func SplitArguments(value string) ([]string, error) {
+	var args []string
+	var word strings.Builder
+	inWord := false
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			if i+1 == len(value) {
+				return nil, fmt.Errorf("%w: trailing backslash", ErrInvalidArguments)
+			}
+			i++
+			word.WriteByte(value[i])
+			inWord = true
+		case c == '\'':
+			end := strings.IndexByte(value[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated single quote", ErrInvalidArguments)
+			}
+			word.WriteString(value[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(value) && value[i] != '"'; i++ {
+				if value[i] == '\\' && i+1 < len(value) && (value[i+1] == '"' || value[i+1] == '\\') {
+					i++
+				}
+				word.WriteByte(value[i])
+			}
+			if i == len(value) {
+				return nil, fmt.Errorf("%w: unterminated double quote", ErrInvalidArguments)
+			}
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrProfileCycle is returned when a profile inheritance chain loops back on itself
+	ErrProfileCycle = errors.New("profile inheritance cycle")
+)
+
+// ProfileLoader loads a profile by name for the binary being resolved
+type ProfileLoader func(profileName string) (*Profile, error)
+
+// ResolveInheritance merges a profile with the chain of profiles it extends.
+// Values defined closer to the given profile override those of its ancestors.
+func ResolveInheritance(profile *Profile, load ProfileLoader) (*Profile, error) {
+	chain := []*Profile{profile}
+	visited := map[string]bool{profile.Name(): true}
+	path := []string{profile.Name()}
+
+	current := profile
+	for current.Parent() != "" {
+		parentName := current.Parent()
+		path = append(path, parentName)
+
+		if visited[parentName] {
+			return nil, fmt.Errorf("%w: %s", ErrProfileCycle, strings.Join(path, " -> "))
+		}
+		visited[parentName] = true
+
+		parent, err := load(parentName)
+		if err != nil {
+			return nil, fmt.Errorf("profile '%s' extends '%s': %w", current.Name(), parentName, err)
+		}
+
+		chain = append(chain, parent)
+		current = parent
+	}
+
+	// Merge from the root ancestor down to the profile itself. Within a
+	// profile, assignments come before directives: an assignment discards what
+	// ancestors did to a key, an unset discards everything before it.
+	env := make(map[string]string)
+	literals := make(map[string]bool)
+	var directives []Directive
+	for i := len(chain) - 1; i >= 0; i-- {
+		for key, value := range chain[i].environment {
+			env[key] = value
+			literals[key] = chain[i].literals[key]
+			directives = withoutKey(directives, key)
+		}
+		for _, d := range chain[i].directives {
+			if d.kind == DirectiveUnset {
+				delete(env, d.key)
+				directives = withoutKey(directives, d.key)
+			}
+			directives = append(directives, d)
+		}
+	}
+
+	resolved, err := NewProfile(profile.Name(), profile.BinaryName(), env)
+	if err != nil {
+		return nil, err
+	}
+	for key := range env {
+		if literals[key] {
+			resolved.AddLiteralVariable(key, env[key])
+		}
+	}
+	resolved.SetParent(profile.Parent())
+	resolved.SetDirectives(directives)
+
+	return resolved, nil
+}
@@ -1,6 +1,15 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// reservedProfileNames are names used by wrapper for its own files
+var reservedProfileNames = map[string]bool{
+	"current": true,
+}
 
 // ProfileName is a value object representing a profile name
 type ProfileName struct {
@@ -12,6 +21,12 @@ func NewProfileName(name string) (ProfileName, error) {
 	if name == "" {
 		return ProfileName{}, errors.New("profile name cannot be empty")
 	}
+	if strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return ProfileName{}, fmt.Errorf("invalid profile name '%s'", name)
+	}
+	if reservedProfileNames[name] {
+		return ProfileName{}, fmt.Errorf("profile name '%s' is reserved", name)
+	}
 	return ProfileName{value: name}, nil
 }
 
@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrInvalidOption is returned when a profile option has an invalid value
+	ErrInvalidOption = errors.New("invalid profile option")
+)
+
+// Profile options are set as regular keys in the profile file. They configure
+// wrapper itself and are never passed to the binary. Being regular keys, they
+// are inherited through extends like any other value.
+const (
+	// OptionProtected requires a typed confirmation before executing the binary
+	OptionProtected = "WRAPPER_PROTECTED"
+	// OptionConfirm restricts the confirmation of a protected profile to
+	// arguments matching one of its comma-separated patterns
+	OptionConfirm = "WRAPPER_CONFIRM"
+	// OptionArgsPrepend holds arguments inserted before the command line arguments
+	OptionArgsPrepend = "WRAPPER_ARGS_PREPEND"
+	// OptionArgsAppend holds arguments added after the command line arguments
+	OptionArgsAppend = "WRAPPER_ARGS_APPEND"
+	// OptionArgsAfterPrefix prefixes the options holding arguments inserted
+	// right after a subcommand, e.g. WRAPPER_ARGS_AFTER_INSTALL
+	OptionArgsAfterPrefix = "WRAPPER_ARGS_AFTER_"
+	// OptionEnvMode selects how profile variables combine with the process
+	// environment, see EnvMode
+	OptionEnvMode = "WRAPPER_ENV_MODE"
+	// OptionCleanEnv starts the environment of the binary from the allowed
+	// process variables only
+	OptionCleanEnv = "WRAPPER_CLEAN_ENV"
+	// OptionEnvAllowlist adds comma-separated variable patterns to
+	// DefaultEnvAllowlist
+	OptionEnvAllowlist = "WRAPPER_ENV_ALLOWLIST"
+)
+
+// DefaultEnvAllowlist holds the process variables kept by a clean environment
+var DefaultEnvAllowlist = []string{
+	"HOME", "USER", "LOGNAME", "SHELL", "PATH", "TERM", "COLORTERM", "LANG", "LC_*", "TZ", "TMPDIR",
+	// Needed by most programs on Windows
+	"SYSTEMROOT", "COMSPEC", "PATHEXT", "TEMP", "TMP", "USERPROFILE",
+}
+
+// optionKeys lists the keys holding profile options
+var optionKeys = map[string]bool{
+	OptionProtected:    true,
+	OptionConfirm:      true,
+	OptionArgsPrepend:  true,
+	OptionArgsAppend:   true,
+	OptionEnvMode:      true,
+	OptionCleanEnv:     true,
+	OptionEnvAllowlist: true,
+}
+
+// IsOptionKey reports whether a key holds a profile option rather than a variable
+func IsOptionKey(key string) bool {
+	return optionKeys[key] || strings.HasPrefix(key, OptionArgsAfterPrefix)
+}
+
+// Variables returns the environment variables passed to the binary, without
+// the profile options
+func (p *Profile) Variables() map[string]string {
+	env := make(map[string]string, len(p.environment))
+	for k, v := range p.environment {
+		if !IsOptionKey(k) {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+// IsProtected reports whether executions require a confirmation
+func (p *Profile) IsProtected() bool {
+	return p.boolOption(OptionProtected)
+}
+
+// ConfirmPatterns returns the argument patterns requiring a confirmation on a
+// protected profile. An empty list means every execution is confirmed.
+func (p *Profile) ConfirmPatterns() []string {
+	return p.listOption(OptionConfirm)
+}
+
+// IsCleanEnv reports whether the binary only gets the allowed process variables
+func (p *Profile) IsCleanEnv() bool {
+	return p.boolOption(OptionCleanEnv)
+}
+
+// EnvAllowlist returns the patterns of the process variables kept by a clean
+// environment
+func (p *Profile) EnvAllowlist() []string {
+	return append(append([]string{}, DefaultEnvAllowlist...), p.listOption(OptionEnvAllowlist)...)
+}
+
+// EnvMode returns how profile variables combine with the process environment,
+// EnvModeOverride by default
+func (p *Profile) EnvMode() (EnvMode, error) {
+	switch mode := EnvMode(strings.TrimSpace(p.environment[OptionEnvMode])); mode {
+	case "":
+		return EnvModeOverride, nil
+	case EnvModeOverride, EnvModeFillMissing:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: %s must be '%s' or '%s', got '%s'",
+			ErrInvalidOption, OptionEnvMode, EnvModeOverride, EnvModeFillMissing, mode)
+	}
+}
+
+// boolOption parses a boolean option, false when unset
+func (p *Profile) boolOption(key string) bool {
+	return IsTrue(p.environment[key])
+}
+
+// IsTrue parses a boolean setting: "1", "true", "yes" and "on" are true
+func IsTrue(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// listOption parses a comma-separated option, skipping empty items
+func (p *Profile) listOption(key string) []string {
+	var items []string
+	for _, item := range strings.Split(p.environment[key], ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
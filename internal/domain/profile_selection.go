@@ -0,0 +1,28 @@
+package domain
+
+import "strings"
+
+const (
+	// ProfileEnvVar selects the profile to use for any binary
+	ProfileEnvVar = "WRAPPER_PROFILE"
+)
+
+// BinaryProfileEnvVar returns the variable selecting the profile of a specific
+// binary, e.g. WRAPPER_PROFILE_VAULT or WRAPPER_PROFILE_DOCKER_COMPOSE
+func BinaryProfileEnvVar(binaryName string) string {
+	return ProfileEnvVar + "_" + envVarSuffix(binaryName)
+}
+
+// envVarSuffix turns a name into a variable name suffix: upper case letters,
+// digits and underscores
+func envVarSuffix(name string) string {
+	var b strings.Builder
+	for _, c := range strings.ToUpper(name) {
+		if (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
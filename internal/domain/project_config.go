@@ -0,0 +1,70 @@
+package domain
+
+import "errors"
+
+var (
+	// ErrProjectConfigNotFound is returned when no project configuration file is found
+	ErrProjectConfigNotFound = errors.New("project configuration not found")
+)
+
+// ProjectConfig pins binaries to profiles for a directory tree, like a
+// committed .wrapper file in a project repository
+type ProjectConfig struct {
+	path     string
+	profiles map[string]string
+	trusted  bool
+}
+
+// NewProjectConfig creates a new ProjectConfig
+func NewProjectConfig(path string, profiles map[string]string, trusted bool) *ProjectConfig {
+	if profiles == nil {
+		profiles = make(map[string]string)
+	}
+
+	return &ProjectConfig{
+		path:     path,
+		profiles: profiles,
+		trusted:  trusted,
+	}
+}
+
+// Path returns the path of the configuration file
+func (c *ProjectConfig) Path() string {
+	return c.path
+}
+
+// Profiles returns the binary to profile mapping
+func (c *ProjectConfig) Profiles() map[string]string {
+	// Return a copy to prevent external modification
+	profiles := make(map[string]string, len(c.profiles))
+	for k, v := range c.profiles {
+		profiles[k] = v
+	}
+	return profiles
+}
+
+// ProfileFor returns the profile pinned for a binary
+func (c *ProjectConfig) ProfileFor(binaryName string) (string, bool) {
+	profile, ok := c.profiles[binaryName]
+	return profile, ok
+}
+
+// IsTrusted reports whether the user allowed this file in its current content
+func (c *ProjectConfig) IsTrusted() bool {
+	return c.trusted
+}
+
+// ProjectConfigFinder defines the interface for locating project configurations
+type ProjectConfigFinder interface {
+	// Find returns the configuration nearest to dir, walking up to the root
+	Find(dir string) (*ProjectConfig, error)
+
+	// Load reads the configuration file at path
+	Load(path string) (*ProjectConfig, error)
+
+	// Allow trusts a configuration file in its current content
+	Allow(path string) error
+
+	// Deny revokes the trust of a configuration file
+	Deny(path string) error
+}
@@ -11,6 +11,11 @@ var (
 	ErrNoCurrentProfile = errors.New("no current profile set")
 	// ErrNoDefaultProfile is returned when no default profile is set
 	ErrNoDefaultProfile = errors.New("no default profile set")
+	// ErrInvalidProfile is returned when the content of a profile cannot be parsed
+	ErrInvalidProfile = errors.New("invalid profile")
+	// ErrInsecurePermissions is returned when a profile file, or its directory,
+	// is accessible by other users
+	ErrInsecurePermissions = errors.New("insecure profile permissions")
 )
 
 // ProfileRepository defines the interface for profile persistence
@@ -21,24 +26,80 @@ type ProfileRepository interface {
 	// FindByName finds a profile by name and binary name
 	FindByName(profileName, binaryName string) (*Profile, error)
 
+	// Exists reports whether a profile is stored, even if it can't be read
+	Exists(profileName, binaryName string) bool
+
+	// Resolve finds a profile by name and merges it with the profiles it extends
+	Resolve(profileName, binaryName string) (*Profile, error)
+
+	// ReadContent reads the raw content of a profile file
+	ReadContent(profileName, binaryName string) ([]byte, error)
+
+	// WriteContent validates raw profile content and atomically replaces the profile file
+	WriteContent(profileName, binaryName string, content []byte) error
+
 	// List lists all profiles for a binary
 	List(binaryName string) ([]*Profile, error)
 
 	// Delete deletes a profile
 	Delete(profileName, binaryName string) error
 
+	// Rename renames a profile, keeping its content unchanged. An existing
+	// profile named newName is atomically replaced when overwrite is set.
+	Rename(profileName, newName, binaryName string, overwrite bool) error
+
+	// Copy copies a profile under a new name, keeping its content unchanged. An
+	// existing profile named newName is atomically replaced when overwrite is set.
+	Copy(profileName, newName, binaryName string, overwrite bool) error
+
+	// Encrypt replaces a plain text profile file with an encrypted one
+	Encrypt(profileName, binaryName string) error
+
+	// Decrypt replaces an encrypted profile file with a plain text one
+	Decrypt(profileName, binaryName string) error
+
 	// SetCurrent sets the current profile for a binary
 	SetCurrent(profileName, binaryName string) error
 
 	// GetCurrent gets the current profile for a binary
 	GetCurrent(binaryName string) (*Profile, error)
 
+	// CurrentName returns the name of the current profile without reading it
+	CurrentName(binaryName string) (string, error)
+
+	// UnsetCurrent clears the current profile for a binary
+	UnsetCurrent(binaryName string) error
+
 	// SetDefault sets the default profile for a binary
 	SetDefault(profileName, binaryName string) error
 
 	// GetDefault gets the default profile for a binary
 	GetDefault(binaryName string) (*Profile, error)
 
-	// GetActiveProfile gets the active profile (current if set, otherwise default)
+	// DefaultName returns the name of the default profile without reading it
+	DefaultName(binaryName string) (string, error)
+
+	// UnsetDefault clears the default profile for a binary
+	UnsetDefault(binaryName string) error
+
+	// GetActiveProfile gets the active profile (current if set, otherwise default),
+	// merged with the profiles it extends
 	GetActiveProfile(binaryName string) (*Profile, error)
+
+	// Lock serializes the updates of the profiles of a binary across processes
+	// until the returned function is called. Held around a read-modify-write,
+	// it keeps concurrent commands from losing each other's updates. Repository
+	// methods called while it is held don't wait for it.
+	Lock(binaryName string) (func(), error)
+}
+
+// IsInsecure reports whether the profile was refused because its file is
+// accessible by other users, in which case its variables were not read
+func (p *Profile) IsInsecure() bool {
+	return p.insecure
+}
+
+// SetInsecure records whether the profile was refused because of its permissions
+func (p *Profile) SetInsecure(insecure bool) {
+	p.insecure = insecure
 }
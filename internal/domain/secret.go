@@ -0,0 +1,198 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidSecretReference is returned when a ref+ value is malformed
+	ErrInvalidSecretReference = errors.New("invalid secret reference")
+	// ErrUnknownSecretProvider is returned when no provider handles a reference scheme
+	ErrUnknownSecretProvider = errors.New("unknown secret provider")
+	// ErrSecretNotFound is returned when a reference points to nothing
+	ErrSecretNotFound = errors.New("secret not found")
+)
+
+const (
+	// SecretReferencePrefix starts values resolved by a secret provider, e.g.
+	// ref+file:///run/secrets/vault or ref+cmd://pass show corp/vault
+	SecretReferencePrefix = "ref+"
+	// secretTTLFragment ends references whose secret can be cached, e.g.
+	// ref+cmd://pass show corp/vault#ttl=5m
+	secretTTLFragment = "#ttl="
+)
+
+// SecretReference points to a secret held outside of the profile
+type SecretReference struct {
+	scheme   string
+	location string
+	ttl      time.Duration
+}
+
+// ParseSecretReference parses a "ref+<scheme>://<location>[#ttl=<duration>]"
+// value. It reports false when the value is not a reference.
+func ParseSecretReference(value string) (SecretReference, bool, error) {
+	if !strings.HasPrefix(value, SecretReferencePrefix) {
+		return SecretReference{}, false, nil
+	}
+
+	scheme, location, ok := strings.Cut(strings.TrimPrefix(value, SecretReferencePrefix), "://")
+	if !ok || scheme == "" || location == "" {
+		return SecretReference{}, true, fmt.Errorf("%w '%s': expected ref+<scheme>://<location>", ErrInvalidSecretReference, value)
+	}
+
+	var ttl time.Duration
+	if i := strings.LastIndex(location, secretTTLFragment); i >= 0 {
+		var err error
+		ttl, err = time.ParseDuration(location[i+len(secretTTLFragment):])
+		if err != nil || ttl <= 0 {
+			return SecretReference{}, true, fmt.Errorf("%w '%s': invalid ttl", ErrInvalidSecretReference, value)
+		}
+		location = location[:i]
+	}
+
+	return SecretReference{scheme: scheme, location: location, ttl: ttl}, true, nil
+}
+
+// Scheme returns the name of the provider handling the reference
+func (r SecretReference) Scheme() string {
+	return r.scheme
+}
+
+// Location returns what the provider resolves, e.g. a path or a command
+func (r SecretReference) Location() string {
+	return r.location
+}
+
+// TTL returns how long the secret may be cached, 0 if it must not
+func (r SecretReference) TTL() time.Duration {
+	return r.ttl
+}
+
+// String returns the reference as written in a profile
+func (r SecretReference) String() string {
+	value := SecretReferencePrefix + r.scheme + "://" + r.location
+	if r.ttl > 0 {
+		value += secretTTLFragment + r.ttl.String()
+	}
+	return value
+}
+
+// SecretProvider fetches the secrets of one reference scheme
+type SecretProvider interface {
+	// Fetch returns the secret stored at a location
+	Fetch(location string) (string, error)
+}
+
+// SecretCache keeps secrets across invocations until they expire
+type SecretCache interface {
+	// Get returns a secret that has not expired yet
+	Get(key string) (string, bool)
+
+	// Put stores a secret for a duration
+	Put(key, value string, ttl time.Duration) error
+
+	// Clear removes all the cached secrets
+	Clear() error
+}
+
+// SecretResolver resolves secret references through the provider registered
+// for their scheme. Each reference is fetched once during the lifetime of the
+// resolver, which is a single invocation. References with a ttl are also kept
+// in the secret cache, when there is one.
+type SecretResolver struct {
+	providers map[string]SecretProvider
+	resolved  map[SecretReference]string
+	cache     SecretCache
+}
+
+// NewSecretResolver creates a new SecretResolver without providers. The cache
+// may be nil.
+func NewSecretResolver(cache SecretCache) *SecretResolver {
+	return &SecretResolver{
+		providers: make(map[string]SecretProvider),
+		resolved:  make(map[SecretReference]string),
+		cache:     cache,
+	}
+}
+
+// Register makes a provider handle the references of a scheme
+func (r *SecretResolver) Register(scheme string, provider SecretProvider) {
+	r.providers[scheme] = provider
+}
+
+// Resolve returns the secret a reference points to. The scope, e.g. the
+// binary and profile name, separates the cached secrets of identical
+// references used by different profiles.
+func (r *SecretResolver) Resolve(scope string, ref SecretReference) (string, error) {
+	if value, ok := r.resolved[ref]; ok {
+		return value, nil
+	}
+
+	cacheKey := scope + "\x00" + ref.String()
+	if r.cache != nil && ref.ttl > 0 {
+		if value, ok := r.cache.Get(cacheKey); ok {
+			r.resolved[ref] = value
+			return value, nil
+		}
+	}
+
+	provider, ok := r.providers[ref.scheme]
+	if !ok {
+		return "", fmt.Errorf("%w '%s' in '%s'", ErrUnknownSecretProvider, ref.scheme, ref)
+	}
+
+	value, err := provider.Fetch(ref.location)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve '%s': %w", ref, err)
+	}
+
+	// The cache is an optimization, failing to fill it doesn't fail the execution
+	if r.cache != nil && ref.ttl > 0 {
+		_ = r.cache.Put(cacheKey, value, ref.ttl)
+	}
+
+	r.resolved[ref] = value
+	return value, nil
+}
+
+// CheckSecrets checks the references found in the values of env without
+// resolving them: they must be well formed and handled by a provider. Errors
+// name the key holding the failing reference.
+func (r *SecretResolver) CheckSecrets(env map[string]string) error {
+	for key, value := range env {
+		ref, ok, err := ParseSecretReference(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if !ok {
+			continue
+		}
+		if _, ok := r.providers[ref.scheme]; !ok {
+			return fmt.Errorf("%s: %w '%s' in '%s'", key, ErrUnknownSecretProvider, ref.scheme, ref)
+		}
+	}
+
+	return nil
+}
+
+// ResolveValue returns the secret the value of a key points to, or the value
+// itself when it is not a reference. See Resolve for the scope.
+func (r *SecretResolver) ResolveValue(scope, key, value string) (string, error) {
+	ref, ok, err := ParseSecretReference(value)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	if !ok {
+		return value, nil
+	}
+
+	secret, err := r.Resolve(scope, ref)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	return secret, nil
+}
@@ -0,0 +1,356 @@
+package infrastructure
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jycamier/wrapper/internal/domain"
+)
+
+// dotenvParser parses the content of a .env profile file.
+//
+// Supported grammar:
+//
+//	# comment
+//	extends <profile>
+//	[export] KEY=value           unquoted, inline " #" comments are stripped
+//	[export] KEY='value'         literal, no escapes, no expansion
+//	[export] KEY="value"         escapes (\n \r \t \\ \" \$), may span multiple lines
+//	[export] KEY+=value          appends to the inherited value, any quoting
+//	[export] KEY^=value          prepends to the inherited value, any quoting
+//	unset KEY                    removes an inherited variable
+type dotenvParser struct {
+	input string
+	pos   int
+	line  int
+}
+
+// parseDotenv parses a .env document, keeping comments, blank lines and ordering
+func parseDotenv(input string) (*envDocument, error) {
+	p := &dotenvParser{input: strings.ReplaceAll(input, "\r\n", "\n"), line: 1}
+	doc := &envDocument{}
+
+	for !p.eof() {
+		lineStart := p.pos
+		startLine := p.line
+		p.skipInlineSpace()
+
+		// Blank lines and comments are kept verbatim
+		if p.eof() || p.peek() == '\n' || p.peek() == '#' {
+			p.skipToLineEnd()
+			doc.lines = append(doc.lines, &envLine{kind: envLineRaw, raw: p.input[lineStart:p.pos]})
+			p.skipNewline()
+			continue
+		}
+
+		line, err := p.readEntry(startLine)
+		if err != nil {
+			return nil, err
+		}
+
+		line.raw = p.input[lineStart:p.pos]
+		doc.lines = append(doc.lines, line)
+		p.skipNewline()
+	}
+
+	return doc, nil
+}
+
+// readEntry reads a directive or an assignment, stopping at the end of its last line
+func (p *dotenvParser) readEntry(startLine int) (*envLine, error) {
+	word := p.readWord()
+
+	// "extends <profile>" directive
+	if word == extendsDirective && p.peekInlineSpace() {
+		p.skipInlineSpace()
+		parent, comment := p.readUnquoted()
+		if parent == "" {
+			return nil, fmt.Errorf("line %d: missing profile name after '%s'", startLine, extendsDirective)
+		}
+		return &envLine{kind: envLineExtends, value: strings.TrimSuffix(parent, ".env"), comment: comment}, nil
+	}
+
+	// "unset KEY" directive
+	if word == unsetDirective && p.peekInlineSpace() {
+		p.skipInlineSpace()
+		key, comment := p.readUnquoted()
+		if !domain.IsValidVariableName(key) {
+			return nil, fmt.Errorf("line %d: invalid key '%s' after '%s'", startLine, key, unsetDirective)
+		}
+		return &envLine{kind: envLineDirective, op: domain.DirectiveUnset, key: key, comment: comment}, nil
+	}
+
+	// Optional "export" prefix
+	exported := false
+	if word == "export" && p.peekInlineSpace() {
+		p.skipInlineSpace()
+		word = p.readWord()
+		exported = true
+	}
+
+	// "+=" and "^=" operators, the word stops right before the '='
+	key := word
+	operator := byte(0)
+	if n := len(key); n > 0 && (key[n-1] == '+' || key[n-1] == '^') {
+		operator = key[n-1]
+		key = key[:n-1]
+	}
+	if !domain.IsValidVariableName(key) {
+		return nil, fmt.Errorf("line %d: invalid key '%s'", startLine, key)
+	}
+
+	p.skipInlineSpace()
+	if operator == 0 && !p.eof() && (p.peek() == '+' || p.peek() == '^') {
+		operator = p.peek()
+		p.advance()
+	}
+	if p.eof() || p.peek() != '=' {
+		return nil, fmt.Errorf("line %d: expected '=' after '%s'", startLine, key)
+	}
+	p.advance()
+	p.skipInlineSpace()
+
+	value, literal, comment, err := p.readValue()
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %s: %w", startLine, key, err)
+	}
+
+	line := &envLine{
+		kind:     envLineAssignment,
+		key:      key,
+		value:    value,
+		literal:  literal,
+		exported: exported,
+		comment:  comment,
+	}
+
+	switch operator {
+	case '+':
+		line.kind, line.op = envLineDirective, domain.DirectiveAppend
+	case '^':
+		line.kind, line.op = envLineDirective, domain.DirectivePrepend
+	}
+
+	return line, nil
+}
+
+// readValue reads a quoted or unquoted value, whether it is literal (never
+// expanded) and the comment trailing it
+func (p *dotenvParser) readValue() (string, bool, string, error) {
+	if p.eof() {
+		return "", false, "", nil
+	}
+
+	var value string
+	var literal bool
+	var err error
+
+	switch p.peek() {
+	case '\'':
+		value, err = p.readSingleQuoted()
+		literal = true
+	case '"':
+		value, literal, err = p.readDoubleQuoted()
+	default:
+		value, comment := p.readUnquoted()
+		return value, false, comment, nil
+	}
+	if err != nil {
+		return "", false, "", err
+	}
+
+	// Only whitespace and a comment may follow a closing quote
+	trailingStart := p.pos
+	p.skipInlineSpace()
+	if !p.eof() && p.peek() != '\n' && p.peek() != '#' {
+		return "", false, "", fmt.Errorf("unexpected characters after closing quote")
+	}
+
+	comment := ""
+	if !p.eof() && p.peek() == '#' {
+		p.skipToLineEnd()
+		comment = p.input[trailingStart:p.pos]
+	}
+
+	return value, literal, comment, nil
+}
+
+// readUnquoted reads until the end of the line, splitting off an inline comment
+func (p *dotenvParser) readUnquoted() (string, string) {
+	start := p.pos
+	for !p.eof() && p.peek() != '\n' {
+		if p.peek() == '#' && p.pos > start && isInlineSpace(p.input[p.pos-1]) {
+			value := strings.TrimRight(p.input[start:p.pos], " \t")
+			commentStart := start + len(value)
+			p.skipToLineEnd()
+			return strings.TrimSpace(value), p.input[commentStart:p.pos]
+		}
+		p.advance()
+	}
+	return strings.TrimSpace(p.input[start:p.pos]), ""
+}
+
+// readSingleQuoted reads a literal value
+func (p *dotenvParser) readSingleQuoted() (string, error) {
+	p.advance() // opening quote
+	start := p.pos
+	for !p.eof() && p.peek() != '\'' {
+		p.advance()
+	}
+	if p.eof() {
+		return "", fmt.Errorf("unterminated single-quoted value")
+	}
+	value := p.input[start:p.pos]
+	p.advance() // closing quote
+
+	return value, nil
+}
+
+// readDoubleQuoted reads a value supporting escape sequences and newlines. A
+// value without unescaped '$' is literal. Otherwise it is expanded, and an
+// escaped "\$" is kept as "$$" to produce a '$' once expanded.
+func (p *dotenvParser) readDoubleQuoted() (string, bool, error) {
+	p.advance() // opening quote
+	var value, template strings.Builder
+	literal := true
+	for !p.eof() {
+		c := p.peek()
+		switch c {
+		case '"':
+			p.advance()
+			if literal {
+				return value.String(), true, nil
+			}
+			return template.String(), false, nil
+		case '\\':
+			p.advance()
+			if p.eof() {
+				return "", false, fmt.Errorf("unterminated double-quoted value")
+			}
+			escaped := p.peek()
+			switch escaped {
+			case 'n':
+				value.WriteByte('\n')
+				template.WriteByte('\n')
+			case 'r':
+				value.WriteByte('\r')
+				template.WriteByte('\r')
+			case 't':
+				value.WriteByte('\t')
+				template.WriteByte('\t')
+			case '\\', '"':
+				value.WriteByte(escaped)
+				template.WriteByte(escaped)
+			case '$':
+				value.WriteByte('$')
+				template.WriteString("$$")
+			default:
+				value.WriteString("\\" + string(escaped))
+				template.WriteString("\\" + string(escaped))
+			}
+			p.advance()
+		default:
+			if c == '$' {
+				literal = false
+			}
+			value.WriteByte(c)
+			template.WriteByte(c)
+			p.advance()
+		}
+	}
+	return "", false, fmt.Errorf("unterminated double-quoted value")
+}
+
+// readWord reads characters up to whitespace, '=' or end of line
+func (p *dotenvParser) readWord() string {
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if c == '=' || c == '\n' || isInlineSpace(c) {
+			break
+		}
+		p.advance()
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *dotenvParser) skipInlineSpace() {
+	for !p.eof() && isInlineSpace(p.peek()) {
+		p.advance()
+	}
+}
+
+func (p *dotenvParser) peekInlineSpace() bool {
+	return !p.eof() && isInlineSpace(p.peek())
+}
+
+// skipToLineEnd advances up to, but not past, the end of the current line
+func (p *dotenvParser) skipToLineEnd() {
+	for !p.eof() && p.peek() != '\n' {
+		p.advance()
+	}
+}
+
+// skipNewline advances past the newline ending the current line, if any
+func (p *dotenvParser) skipNewline() {
+	if !p.eof() && p.peek() == '\n' {
+		p.advance()
+	}
+}
+
+func (p *dotenvParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *dotenvParser) peek() byte {
+	return p.input[p.pos]
+}
+
+func (p *dotenvParser) advance() {
+	if p.input[p.pos] == '\n' {
+		p.line++
+	}
+	p.pos++
+}
+
+func isInlineSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// formatDotenvValue renders a value so that parseDotenv reads it back
+// unchanged. Literal values containing '$' are quoted so they are not expanded.
+func formatDotenvValue(value string, literal bool) string {
+	literal = literal && strings.Contains(value, "$")
+	if value == "" || !(literal || needsQuoting(value)) {
+		return value
+	}
+
+	// Single quotes keep a literal value readable
+	if literal && !strings.ContainsAny(value, "'\r") {
+		return "'" + value + "'"
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '\\':
+			b.WriteString(`\\`)
+		case c == '"':
+			b.WriteString(`\"`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == '$' && literal:
+			b.WriteString(`\$`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// needsQuoting checks whether an unquoted value would be parsed differently
+func needsQuoting(value string) bool {
+	return strings.ContainsAny(value, " \t#'\"\\\n\r")
+}
@@ -0,0 +1,242 @@
+package infrastructure
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jycamier/wrapper/internal/domain"
+)
+
+// envLineKind identifies the kind of a line in a .env document
+type envLineKind int
+
+const (
+	// envLineRaw is a blank line or a comment, kept verbatim
+	envLineRaw envLineKind = iota
+	// envLineAssignment is a KEY=VALUE line
+	envLineAssignment
+	// envLineExtends is an "extends <profile>" directive
+	envLineExtends
+	// envLineDirective is an "unset KEY", "KEY+=value" or "KEY^=value" line
+	envLineDirective
+)
+
+// envLine is a single entry of a .env document. Multi-line values span
+// several physical lines but are held by a single envLine.
+type envLine struct {
+	kind     envLineKind
+	op       domain.DirectiveKind
+	raw      string
+	key      string
+	value    string
+	literal  bool
+	exported bool
+	comment  string
+}
+
+// render rebuilds the raw text of a line after its value changed
+func (l *envLine) render() {
+	switch l.kind {
+	case envLineExtends:
+		l.raw = extendsDirective + " " + l.value + l.comment
+	case envLineAssignment:
+		prefix := ""
+		if l.exported {
+			prefix = "export "
+		}
+		l.raw = prefix + l.key + "=" + formatDotenvValue(l.value, l.literal) + l.comment
+	case envLineDirective:
+		if l.op == domain.DirectiveUnset {
+			l.raw = unsetDirective + " " + l.key + l.comment
+			return
+		}
+		prefix := ""
+		if l.exported {
+			prefix = "export "
+		}
+		operator := "+="
+		if l.op == domain.DirectivePrepend {
+			operator = "^="
+		}
+		l.raw = prefix + l.key + operator + formatDotenvValue(l.value, l.literal) + l.comment
+	}
+}
+
+// directive returns the directive held by a directive line
+func (l *envLine) directive() domain.Directive {
+	return domain.NewDirective(l.op, l.key, l.value, l.literal)
+}
+
+// envDocument is an ordered representation of a .env file that preserves
+// comments, blank lines and formatting of untouched entries
+type envDocument struct {
+	lines []*envLine
+}
+
+// parent returns the profile declared by the extends directive
+func (d *envDocument) parent() string {
+	parent := ""
+	for _, line := range d.lines {
+		if line.kind == envLineExtends {
+			parent = line.value
+		}
+	}
+	return parent
+}
+
+// assignments returns the line assigning each key. When a key is assigned
+// several times the last assignment wins.
+func (d *envDocument) assignments() map[string]*envLine {
+	assignments := make(map[string]*envLine)
+	for _, line := range d.lines {
+		if line.kind == envLineAssignment {
+			assignments[line.key] = line
+		}
+	}
+	return assignments
+}
+
+// directives returns the directives of the document, in order
+func (d *envDocument) directives() []domain.Directive {
+	var directives []domain.Directive
+	for _, line := range d.lines {
+		if line.kind == envLineDirective {
+			directives = append(directives, line.directive())
+		}
+	}
+	return directives
+}
+
+// profile builds the profile described by the document
+func (d *envDocument) profile(profileName, binaryName string) (*domain.Profile, error) {
+	profile, err := domain.NewProfile(profileName, binaryName, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, line := range d.assignments() {
+		if line.literal {
+			profile.AddLiteralVariable(key, line.value)
+		} else {
+			profile.AddEnvironmentVariable(key, line.value)
+		}
+	}
+	profile.SetParent(d.parent())
+	profile.SetDirectives(d.directives())
+
+	return profile, nil
+}
+
+// apply updates the document to match a profile, only touching the lines
+// whose content changed. New variables are appended in sorted order, new
+// directives in their order.
+func (d *envDocument) apply(profile *domain.Profile) {
+	env := profile.Environment()
+
+	// Directives of the profile not matched by a line yet
+	pending := make(map[domain.Directive]int)
+	for _, directive := range profile.Directives() {
+		pending[directive]++
+	}
+
+	// Index the last assignment of each key, earlier ones are shadowed
+	last := d.assignments()
+	var extends *envLine
+	for _, line := range d.lines {
+		if line.kind == envLineExtends {
+			extends = line
+		}
+	}
+
+	kept := make([]*envLine, 0, len(d.lines))
+	for _, line := range d.lines {
+		switch line.kind {
+		case envLineAssignment:
+			value, ok := env[line.key]
+			if !ok || last[line.key] != line {
+				continue
+			}
+			if value != line.value || profile.IsLiteral(line.key) != line.literal {
+				line.value = value
+				line.literal = profile.IsLiteral(line.key)
+				line.render()
+			}
+		case envLineExtends:
+			if line != extends || profile.Parent() == "" {
+				continue
+			}
+			if profile.Parent() != line.value {
+				line.value = profile.Parent()
+				line.render()
+			}
+		case envLineDirective:
+			if pending[line.directive()] == 0 {
+				continue
+			}
+			pending[line.directive()]--
+		}
+		kept = append(kept, line)
+	}
+	d.lines = kept
+
+	if extends == nil && profile.Parent() != "" {
+		line := &envLine{kind: envLineExtends, value: profile.Parent()}
+		line.render()
+		d.insertBeforeAssignments(line)
+	}
+
+	var added []string
+	for key := range env {
+		if _, ok := last[key]; !ok {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+
+	for _, key := range added {
+		line := &envLine{kind: envLineAssignment, key: key, value: env[key], literal: profile.IsLiteral(key)}
+		line.render()
+		d.lines = append(d.lines, line)
+	}
+
+	for _, directive := range profile.Directives() {
+		if pending[directive] == 0 {
+			continue
+		}
+		pending[directive]--
+
+		line := &envLine{
+			kind:    envLineDirective,
+			op:      directive.Kind(),
+			key:     directive.Key(),
+			value:   directive.Value(),
+			literal: directive.IsLiteral(),
+		}
+		line.render()
+		d.lines = append(d.lines, line)
+	}
+}
+
+// insertBeforeAssignments inserts a line before the first assignment
+func (d *envDocument) insertBeforeAssignments(line *envLine) {
+	index := len(d.lines)
+	for i, existing := range d.lines {
+		if existing.kind != envLineRaw {
+			index = i
+			break
+		}
+	}
+
+	d.lines = append(d.lines, nil)
+	copy(d.lines[index+1:], d.lines[index:])
+	d.lines[index] = line
+}
+
+// String renders the document as .env file content
+func (d *envDocument) String() string {
+	var b strings.Builder
+	for _, line := range d.lines {
+		b.WriteString(line.raw)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
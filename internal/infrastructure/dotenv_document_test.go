@@ -0,0 +1,172 @@
+package infrastructure
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jycamier/wrapper/internal/domain"
+)
+
+func TestEnvDocumentApply(t *testing.T) {
+	const document = `# Production settings
+extends base
+
+# Endpoint
+export VAULT_ADDR=https://vault.example.com # main cluster
+PASSWORD='pa$word'
+CERT="-----BEGIN-----
+abc
+-----END-----"
+PATH^=/opt/vault/bin
+unset VAULT_TOKEN
+`
+
+	tests := []struct {
+		name   string
+		input  string
+		update func(p *domain.Profile)
+		want   string
+	}{
+		{
+			name:   "unchanged profile",
+			input:  document,
+			update: func(p *domain.Profile) {},
+			want:   document,
+		},
+		{
+			name:  "updated values keep their comment, prefix and position",
+			input: document,
+			update: func(p *domain.Profile) {
+				p.AddEnvironmentVariable("VAULT_ADDR", "https://vault 2")
+				p.AddLiteralVariable("PASSWORD", "new$pass")
+			},
+			want: `# Production settings
+extends base
+
+# Endpoint
+export VAULT_ADDR="https://vault 2" # main cluster
+PASSWORD='new$pass'
+CERT="-----BEGIN-----
+abc
+-----END-----"
+PATH^=/opt/vault/bin
+unset VAULT_TOKEN
+`,
+		},
+		{
+			name:  "removed variables and directives drop their lines only",
+			input: document,
+			update: func(p *domain.Profile) {
+				p.RemoveEnvironmentVariable("VAULT_ADDR")
+				p.RemoveEnvironmentVariable("CERT")
+				p.SetDirectives(p.Directives()[1:])
+			},
+			want: `# Production settings
+extends base
+
+# Endpoint
+PASSWORD='pa$word'
+unset VAULT_TOKEN
+`,
+		},
+		{
+			name:  "added variables and directives are appended",
+			input: document,
+			update: func(p *domain.Profile) {
+				p.AddEnvironmentVariable("ZONE", "eu west")
+				p.AddLiteralVariable("API_KEY", "$ecret")
+				p.SetDirectives(append(p.Directives(), domain.NewDirective(domain.DirectiveAppend, "PATH", "/usr/local/bin", false)))
+			},
+			want: document + `API_KEY='$ecret'
+ZONE="eu west"
+PATH+=/usr/local/bin
+`,
+		},
+		{
+			name:  "switching a value to literal quotes it",
+			input: "# Tokens\nTOKEN=$OTHER # from the shell\n",
+			update: func(p *domain.Profile) {
+				p.AddLiteralVariable("TOKEN", "$OTHER")
+			},
+			want: "# Tokens\nTOKEN='$OTHER' # from the shell\n",
+		},
+		{
+			name:  "added parent goes before the first entry",
+			input: "# Staging\n\nKEY=value\n",
+			update: func(p *domain.Profile) {
+				p.SetParent("base")
+			},
+			want: "# Staging\n\nextends base\nKEY=value\n",
+		},
+		{
+			name:  "changed and removed parents",
+			input: "extends base # shared\nextends other\nKEY=value\n",
+			update: func(p *domain.Profile) {
+				p.SetParent("prod")
+			},
+			want: "extends prod\nKEY=value\n",
+		},
+		{
+			name:  "shadowed assignments are dropped",
+			input: "KEY=first\n# kept\nKEY=second\n",
+			update: func(p *domain.Profile) {
+				p.AddEnvironmentVariable("KEY", "third")
+			},
+			want: "# kept\nKEY=third\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseDotenv(tt.input)
+			if err != nil {
+				t.Fatalf("parseDotenv() error = %v", err)
+			}
+			profile, err := doc.profile("prod", "vault")
+			if err != nil {
+				t.Fatalf("profile() error = %v", err)
+			}
+
+			tt.update(profile)
+			doc.apply(profile)
+
+			got := doc.String()
+			if got != tt.want {
+				t.Errorf("apply() rendered:\n%s\nwant:\n%s", got, tt.want)
+			}
+
+			// The rendered document describes the updated profile
+			reread, err := parseDotenv(got)
+			if err != nil {
+				t.Fatalf("parseDotenv() of the rendered document error = %v", err)
+			}
+			readBack, err := reread.profile("prod", "vault")
+			if err != nil {
+				t.Fatalf("profile() error = %v", err)
+			}
+			assertSameProfile(t, readBack, profile)
+		})
+	}
+}
+
+// assertSameProfile checks that two profiles hold the same variables, parent
+// and directives. Being literal only matters for values with references.
+func assertSameProfile(t *testing.T, got, want *domain.Profile) {
+	t.Helper()
+
+	if !reflect.DeepEqual(got.Environment(), want.Environment()) {
+		t.Errorf("environment = %q, want %q", got.Environment(), want.Environment())
+	}
+	for key, value := range want.Environment() {
+		if strings.Contains(value, "$") && got.IsLiteral(key) != want.IsLiteral(key) {
+			t.Errorf("IsLiteral(%s) = %v, want %v", key, got.IsLiteral(key), want.IsLiteral(key))
+		}
+	}
+	if got.Parent() != want.Parent() {
+		t.Errorf("parent = %q, want %q", got.Parent(), want.Parent())
+	}
+	if !reflect.DeepEqual(got.Directives(), want.Directives()) {
+		t.Errorf("directives = %+v, want %+v", got.Directives(), want.Directives())
+	}
+}
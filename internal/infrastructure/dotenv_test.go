@@ -0,0 +1,244 @@
+package infrastructure
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jycamier/wrapper/internal/domain"
+)
+
+func TestParseDotenv(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []envLine
+		wantErr string
+	}{
+		{
+			name:  "unquoted value",
+			input: "  KEY = some value  ",
+			want:  []envLine{{kind: envLineAssignment, key: "KEY", value: "some value"}},
+		},
+		{
+			name:  "empty value",
+			input: "KEY=",
+			want:  []envLine{{kind: envLineAssignment, key: "KEY"}},
+		},
+		{
+			name:  "inline comment after a space",
+			input: "KEY=value # note\nURL=http://host/#anchor",
+			want: []envLine{
+				{kind: envLineAssignment, key: "KEY", value: "value", comment: " # note"},
+				{kind: envLineAssignment, key: "URL", value: "http://host/#anchor"},
+			},
+		},
+		{
+			name:  "single quotes are literal",
+			input: `KEY='a $b \n "c"' # note`,
+			want:  []envLine{{kind: envLineAssignment, key: "KEY", value: `a $b \n "c"`, literal: true, comment: " # note"}},
+		},
+		{
+			name:  "double quotes support escapes",
+			input: `KEY="a\tb\\c\"d\re\qf # not a comment"`,
+			want:  []envLine{{kind: envLineAssignment, key: "KEY", value: "a\tb\\c\"d\re\\qf # not a comment", literal: true}},
+		},
+		{
+			name:  "double quotes with references are expanded",
+			input: `KEY="${HOME}/bin"`,
+			want:  []envLine{{kind: envLineAssignment, key: "KEY", value: "${HOME}/bin"}},
+		},
+		{
+			name:  "escaped dollar in an expanded value",
+			input: `KEY="\$HOME is $HOME"`,
+			want:  []envLine{{kind: envLineAssignment, key: "KEY", value: "$$HOME is $HOME"}},
+		},
+		{
+			name:  "escaped dollar only makes a literal value",
+			input: `KEY="costs \$5"`,
+			want:  []envLine{{kind: envLineAssignment, key: "KEY", value: "costs $5", literal: true}},
+		},
+		{
+			name:  "multi-line values",
+			input: "CERT=\"-----BEGIN-----\nabc\n-----END-----\" # pem\nNEXT='one\ntwo'",
+			want: []envLine{
+				{kind: envLineAssignment, key: "CERT", value: "-----BEGIN-----\nabc\n-----END-----", literal: true, comment: " # pem"},
+				{kind: envLineAssignment, key: "NEXT", value: "one\ntwo", literal: true},
+			},
+		},
+		{
+			name:  "export prefix",
+			input: "export KEY=value\nexport\tOTHER='x'\nexport=1",
+			want: []envLine{
+				{kind: envLineAssignment, key: "KEY", value: "value", exported: true},
+				{kind: envLineAssignment, key: "OTHER", value: "x", literal: true, exported: true},
+				{kind: envLineAssignment, key: "export", value: "1"},
+			},
+		},
+		{
+			name:  "comments and blank lines are kept verbatim",
+			input: "# header\n\n   # indented\nKEY=1\n\t\n",
+			want: []envLine{
+				{kind: envLineRaw, raw: "# header"},
+				{kind: envLineRaw, raw: ""},
+				{kind: envLineRaw, raw: "   # indented"},
+				{kind: envLineAssignment, key: "KEY", value: "1"},
+				{kind: envLineRaw, raw: "\t"},
+			},
+		},
+		{
+			name:  "windows line endings",
+			input: "A=1\r\nB=\"2\"\r\n",
+			want: []envLine{
+				{kind: envLineAssignment, key: "A", value: "1"},
+				{kind: envLineAssignment, key: "B", value: "2", literal: true},
+			},
+		},
+		{
+			name:  "append and prepend directives",
+			input: "PATH+=/opt/bin\nPATH ^= \"/a b\" # first\nexport MANPATH+='$X'",
+			want: []envLine{
+				{kind: envLineDirective, op: domain.DirectiveAppend, key: "PATH", value: "/opt/bin"},
+				{kind: envLineDirective, op: domain.DirectivePrepend, key: "PATH", value: "/a b", literal: true, comment: " # first"},
+				{kind: envLineDirective, op: domain.DirectiveAppend, key: "MANPATH", value: "$X", literal: true, exported: true},
+			},
+		},
+		{
+			name:  "unset directive",
+			input: "unset AWS_PROFILE # use the default\nunset=1",
+			want: []envLine{
+				{kind: envLineDirective, op: domain.DirectiveUnset, key: "AWS_PROFILE", comment: " # use the default"},
+				{kind: envLineAssignment, key: "unset", value: "1"},
+			},
+		},
+		{
+			name:  "extends directive",
+			input: "extends base.env # shared\nextends=1",
+			want: []envLine{
+				{kind: envLineExtends, value: "base", comment: " # shared"},
+				{kind: envLineAssignment, key: "extends", value: "1"},
+			},
+		},
+		{
+			name:    "invalid key",
+			input:   "A=1\n1KEY=value",
+			wantErr: "line 2: invalid key '1KEY'",
+		},
+		{
+			name:    "missing equal sign",
+			input:   "KEY value",
+			wantErr: "line 1: expected '=' after 'KEY'",
+		},
+		{
+			name:    "line numbers count the lines of multi-line values",
+			input:   "A=\"x\ny\"\nB='z\n",
+			wantErr: "line 3: B: unterminated single-quoted value",
+		},
+		{
+			name:    "unterminated double-quoted value",
+			input:   `KEY="value\"`,
+			wantErr: "line 1: KEY: unterminated double-quoted value",
+		},
+		{
+			name:    "characters after a closing quote",
+			input:   `KEY="value" other`,
+			wantErr: "line 1: KEY: unexpected characters after closing quote",
+		},
+		{
+			name:    "unset without a valid key",
+			input:   "unset 1KEY",
+			wantErr: "line 1: invalid key '1KEY' after 'unset'",
+		},
+		{
+			name:    "extends without a profile",
+			input:   "extends \t",
+			wantErr: "line 1: missing profile name after 'extends'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseDotenv(tt.input)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("parseDotenv() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDotenv() error = %v", err)
+			}
+
+			if got := parsedLines(doc); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDotenv() = %+v, want %+v", got, tt.want)
+			}
+
+			// Untouched documents are rendered as they were read
+			want := strings.ReplaceAll(tt.input, "\r\n", "\n")
+			if !strings.HasSuffix(want, "\n") {
+				want += "\n"
+			}
+			if got := doc.String(); got != want {
+				t.Errorf("String() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestFormatDotenvValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		literal bool
+		want    string
+	}{
+		{name: "plain", value: "value", want: "value"},
+		{name: "empty", value: "", want: ""},
+		{name: "plain literal", value: "value", literal: true, want: "value"},
+		{name: "spaces", value: "a b", want: `"a b"`},
+		{name: "comment", value: "a #b", want: `"a #b"`},
+		{name: "quotes and backslashes", value: `it's "C:\dir"`, want: `"it's \"C:\\dir\""`},
+		{name: "newlines", value: "one\ntwo\r\n", want: "\"one\ntwo\\r\n\""},
+		{name: "reference", value: "${HOME}/bin", want: "${HOME}/bin"},
+		{name: "reference with spaces", value: "$HOME is home", want: `"$HOME is home"`},
+		{name: "literal dollar", value: "pa$word", literal: true, want: "'pa$word'"},
+		{name: "literal dollar and quote", value: "it's $5", literal: true, want: `"it's \$5"`},
+		{name: "literal dollar and carriage return", value: "$a\r", literal: true, want: `"\$a\r"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatDotenvValue(tt.value, tt.literal)
+			if got != tt.want {
+				t.Errorf("formatDotenvValue() = %q, want %q", got, tt.want)
+			}
+
+			// The formatted value is read back unchanged
+			doc, err := parseDotenv("KEY=" + got)
+			if err != nil {
+				t.Fatalf("parseDotenv() error = %v", err)
+			}
+			lines := parsedLines(doc)
+			if len(lines) != 1 || lines[0].value != tt.value {
+				t.Fatalf("parseDotenv() = %+v, want value %q", lines, tt.value)
+			}
+			if tt.literal && strings.Contains(tt.value, "$") && !lines[0].literal {
+				t.Errorf("parseDotenv() read literal value %q as expanded", tt.value)
+			}
+		})
+	}
+}
+
+// parsedLines returns the lines of a document, without the raw text of the
+// entries to keep expectations short
+func parsedLines(doc *envDocument) []envLine {
+	lines := make([]envLine, 0, len(doc.lines))
+	for _, line := range doc.lines {
+		parsed := *line
+		if parsed.kind != envLineRaw {
+			parsed.raw = ""
+		}
+		lines = append(lines, parsed)
+	}
+	return lines
+}
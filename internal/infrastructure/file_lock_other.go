@@ -0,0 +1,16 @@
+//go:build !linux && !darwin
+
+package infrastructure
+
+import "os"
+
+// lockFile is not supported on this platform: writes are still atomic but
+// concurrent updates are not serialized
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile is not supported on this platform
+func unlockFile(file *os.File) error {
+	return nil
+}
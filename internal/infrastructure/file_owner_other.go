@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package infrastructure
+
+import "os"
+
+// fileOwnedByOtherUser is not supported on this platform
+func fileOwnedByOtherUser(info os.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin
+
+package infrastructure
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileOwnedByOtherUser reports whether a file is owned by another user than
+// the one running the wrapper
+func fileOwnedByOtherUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) != os.Getuid()
+}
@@ -0,0 +1,127 @@
+package infrastructure
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/jycamier/wrapper/internal/domain"
+)
+
+// Profiles and caches hold secrets: files and directories are private to their owner
+const (
+	privateFileMode os.FileMode = 0600
+	privateDirMode  os.FileMode = 0700
+)
+
+// PermissionCheckEnvVar selects how profiles accessible by other users are
+// handled: warn (default), strict or off
+const PermissionCheckEnvVar = "WRAPPER_PERMISSION_CHECK"
+
+// PermissionCheck is the handling of profiles accessible by other users
+type PermissionCheck string
+
+const (
+	// PermissionCheckWarn prints a warning and reads the profile anyway
+	PermissionCheckWarn PermissionCheck = "warn"
+	// PermissionCheckStrict refuses to read the profile
+	PermissionCheckStrict PermissionCheck = "strict"
+	// PermissionCheckOff disables the check
+	PermissionCheckOff PermissionCheck = "off"
+)
+
+// permissionCheck returns the configured handling, warn when unset or unknown
+func permissionCheck() PermissionCheck {
+	switch check := PermissionCheck(os.Getenv(PermissionCheckEnvVar)); check {
+	case PermissionCheckStrict, PermissionCheckOff:
+		return check
+	default:
+		return PermissionCheckWarn
+	}
+}
+
+// auditPermissions returns why a profile file or directory is insecure, like
+// ssh does for private keys: it is owned by another user or accessible by
+// group or others.
+// Windows doesn't expose POSIX permissions and is never reported.
+func auditPermissions(info os.FileInfo) string {
+	if runtime.GOOS == "windows" {
+		return ""
+	}
+
+	if fileOwnedByOtherUser(info) {
+		return "is owned by another user"
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return fmt.Sprintf("is accessible by other users (mode %04o)", perm)
+	}
+
+	return ""
+}
+
+// makePrivateDir creates a directory private to its owner. An existing
+// directory accessible by other users, e.g. created by an earlier version, is
+// restricted to its owner.
+func makePrivateDir(dir string) error {
+	if err := os.MkdirAll(dir, privateDirMode); err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 && !fileOwnedByOtherUser(info) {
+		return os.Chmod(dir, privateDirMode)
+	}
+
+	return nil
+}
+
+// checkProfilePermissions audits a profile file along with the directory of
+// its binary and the base directory. Depending on WRAPPER_PERMISSION_CHECK, an
+// insecure file or directory is reported once on stderr or refused with
+// ErrInsecurePermissions.
+func (r *FilesystemRepository) checkProfilePermissions(path string) error {
+	check := permissionCheck()
+	if check == PermissionCheckOff {
+		return nil
+	}
+
+	if err := checkPermissions(check, r.baseDir, privateDirMode, r.warned); err != nil {
+		return err
+	}
+	if err := checkPermissions(check, filepath.Dir(path), privateDirMode, r.warned); err != nil {
+		return err
+	}
+	return checkPermissions(check, path, privateFileMode, r.warned)
+}
+
+// checkPermissions audits a single file or directory, see checkProfilePermissions.
+// Paths already reported in warned are not reported again.
+func checkPermissions(check PermissionCheck, path string, mode os.FileMode, warned map[string]bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	problem := auditPermissions(info)
+	if problem == "" {
+		return nil
+	}
+
+	if check == PermissionCheckStrict {
+		return fmt.Errorf("%w: %s %s", domain.ErrInsecurePermissions, path, problem)
+	}
+
+	if !warned[path] {
+		warned[path] = true
+		fmt.Fprintf(os.Stderr, "wrapper: warning: %s %s, run 'chmod %o %s'\n", path, problem, mode, path)
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package infrastructure
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileSecretCache keeps secrets in files under ~/.cache/wrapper/secrets,
+// encrypted with AES-256-GCM. The key lives in the runtime directory of the
+// user (XDG_RUNTIME_DIR, usually a tmpfs cleared at logout) so that the cache
+// is unreadable once the session ends, or from a copy of the home directory.
+type FileSecretCache struct {
+	dir        string
+	runtimeDir string
+	keyFile    string
+}
+
+// NewFileSecretCache creates a new FileSecretCache
+func NewFileSecretCache() (*FileSecretCache, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cache directory: %w", err)
+	}
+
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		runtimeDir = filepath.Join(os.TempDir(), fmt.Sprintf("wrapper-%d", os.Getuid()))
+	}
+
+	return &FileSecretCache{
+		dir:        filepath.Join(cacheDir, "wrapper", "secrets"),
+		runtimeDir: runtimeDir,
+		keyFile:    filepath.Join(runtimeDir, "wrapper", "cache.key"),
+	}, nil
+}
+
+// Get returns a secret that has not expired yet
+func (c *FileSecretCache) Get(key string) (string, bool) {
+	aead, err := c.aead(false)
+	if err != nil {
+		return "", false
+	}
+
+	name := c.entryName(key)
+	path := filepath.Join(c.dir, name)
+
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", false
+	}
+
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(name))
+	if err != nil {
+		_ = os.Remove(path)
+		return "", false
+	}
+
+	// Entries hold the expiration time, then the secret
+	expires, value, ok := strings.Cut(string(plaintext), "\n")
+	deadline, err := strconv.ParseInt(expires, 10, 64)
+	if !ok || err != nil || time.Now().Unix() >= deadline {
+		_ = os.Remove(path)
+		return "", false
+	}
+
+	return value, true
+}
+
+// Put stores a secret for a duration
+func (c *FileSecretCache) Put(key, value string, ttl time.Duration) error {
+	aead, err := c.aead(true)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(c.dir, privateDirMode); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	name := c.entryName(key)
+	plaintext := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10) + "\n" + value
+	data := append(nonce, aead.Seal(nil, nonce, []byte(plaintext), []byte(name))...)
+
+	if err := writeFileAtomic(filepath.Join(c.dir, name), data, privateFileMode); err != nil {
+		return fmt.Errorf("failed to write cached secret: %w", err)
+	}
+
+	return nil
+}
+
+// Clear removes all the cached secrets and the cache key
+func (c *FileSecretCache) Clear() error {
+	if err := os.RemoveAll(c.dir); err != nil {
+		return fmt.Errorf("failed to clear secret cache: %w", err)
+	}
+	if err := os.Remove(c.keyFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove cache key: %w", err)
+	}
+	return nil
+}
+
+// entryName returns the file name of a cache entry, which doesn't reveal the
+// reference it holds
+func (c *FileSecretCache) entryName(key string) string {
+	return contentHash([]byte(key))
+}
+
+// aead returns the cipher of the cache, creating its key if needed
+func (c *FileSecretCache) aead(create bool) (cipher.AEAD, error) {
+	encoded, err := c.readKey()
+	if os.IsNotExist(err) && create {
+		encoded, err = c.createKey()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache key: %w", err)
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("invalid cache key %s", c.keyFile)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// readKey reads the cache key, once its directories are checked
+func (c *FileSecretCache) readKey() ([]byte, error) {
+	if err := c.checkKeyDirs(); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(c.keyFile)
+}
+
+// createKey generates a random cache key. Entries encrypted with a previous
+// key can't be read anymore and are dropped when they are looked up.
+func (c *FileSecretCache) createKey() ([]byte, error) {
+	if err := os.MkdirAll(filepath.Dir(c.keyFile), privateDirMode); err != nil {
+		return nil, err
+	}
+	if err := c.checkKeyDirs(); err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	encoded := []byte(hex.EncodeToString(key))
+	if err := writeFileAtomic(c.keyFile, encoded, privateFileMode); err != nil {
+		return nil, err
+	}
+
+	return encoded, nil
+}
+
+// checkKeyDirs makes sure that only the current user can reach the cache key:
+// the runtime directory and the key directory must be directories owned by
+// the user and closed to other users. The fallback runtime directory is in the
+// shared temporary directory, where another user could create it first.
+func (c *FileSecretCache) checkKeyDirs() error {
+	for _, dir := range []string{c.runtimeDir, filepath.Dir(c.keyFile)} {
+		info, err := os.Lstat(dir)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case !info.IsDir():
+			return fmt.Errorf("%s is not a directory", dir)
+		case fileOwnedByOtherUser(info):
+			return fmt.Errorf("%s is owned by another user", dir)
+		case runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0:
+			return fmt.Errorf("%s is accessible by other users", dir)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package infrastructure
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jycamier/wrapper/internal/domain"
+)
+
+// FilesystemHookRepository implements HookRepository using executables stored
+// next to the profiles:
+//
+//	~/.config/wrapper/<binary>/hooks/pre.d/*             every profile
+//	~/.config/wrapper/<binary>/hooks/<profile>/pre.d/*   a single profile
+//
+// and likewise for post.d.
+type FilesystemHookRepository struct {
+	baseDir string
+}
+
+// NewFilesystemHookRepository creates a new FilesystemHookRepository
+func NewFilesystemHookRepository() (*FilesystemHookRepository, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	baseDir := filepath.Join(homeDir, ".config", "wrapper")
+	return &FilesystemHookRepository{baseDir: baseDir}, nil
+}
+
+// FindHooks returns the binary-wide hooks followed by the profile hooks, each
+// group sorted by file name
+func (r *FilesystemHookRepository) FindHooks(binaryName, profileName string, stage domain.HookStage) ([]*domain.Hook, error) {
+	hooksDir := filepath.Join(r.baseDir, binaryName, "hooks")
+	stageDir := string(stage) + ".d"
+
+	binaryHooks, err := r.readHookDir(filepath.Join(hooksDir, stageDir), stage)
+	if err != nil {
+		return nil, err
+	}
+
+	profileHooks, err := r.readHookDir(filepath.Join(hooksDir, profileName, stageDir), stage)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(binaryHooks, profileHooks...), nil
+}
+
+// readHookDir lists the executables of a hook directory
+func (r *FilesystemHookRepository) readHookDir(dir string, stage domain.HookStage) ([]*domain.Hook, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hook directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+
+		// Skip hidden and backup files
+		if strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~") {
+			continue
+		}
+
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hooks := make([]*domain.Hook, 0, len(names))
+	for _, name := range names {
+		hooks = append(hooks, domain.NewHook(filepath.Join(dir, name), stage))
+	}
+
+	return hooks, nil
+}
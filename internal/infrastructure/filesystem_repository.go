@@ -1,7 +1,7 @@
 package infrastructure
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,20 +10,46 @@ import (
 	"github.com/jycamier/wrapper/internal/domain"
 )
 
-// FilesystemRepository implements ProfileRepository using the filesystem
+const (
+	// extendsDirective is the keyword declaring the parent of a profile
+	extendsDirective = "extends"
+	// unsetDirective is the keyword removing an inherited variable
+	unsetDirective = "unset"
+	// encryptedSuffix is appended to the file name of encrypted profiles
+	encryptedSuffix = ".enc"
+	// lockFileName is the file locked while the profiles of a binary are updated
+	lockFileName = ".lock"
+)
+
+// FilesystemRepository implements ProfileRepository using the filesystem.
+// Profiles are stored as <name>.env files, or <name>.env.enc once encrypted.
 type FilesystemRepository struct {
 	baseDir string
+	cipher  domain.ProfileCipher
+	warned  map[string]bool
+	locks   map[string]*heldLock
+}
+
+// heldLock is a lock file locked by this process, and how many times
+type heldLock struct {
+	file  *os.File
+	depth int
 }
 
 // NewFilesystemRepository creates a new FilesystemRepository
-func NewFilesystemRepository() (*FilesystemRepository, error) {
+func NewFilesystemRepository(cipher domain.ProfileCipher) (*FilesystemRepository, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get home directory: %w", err)
 	}
 
 	baseDir := filepath.Join(homeDir, ".config", "wrapper")
-	return &FilesystemRepository{baseDir: baseDir}, nil
+	return &FilesystemRepository{
+		baseDir: baseDir,
+		cipher:  cipher,
+		warned:  make(map[string]bool),
+		locks:   make(map[string]*heldLock),
+	}, nil
 }
 
 // getBinaryDir returns the directory for a specific binary
@@ -31,11 +57,37 @@ func (r *FilesystemRepository) getBinaryDir(binaryName string) string {
 	return filepath.Join(r.baseDir, binaryName)
 }
 
+// makeBinaryDir creates the directory of a binary, keeping it and the base
+// directory private
+func (r *FilesystemRepository) makeBinaryDir(binaryName string) error {
+	if err := makePrivateDir(r.baseDir); err != nil {
+		return err
+	}
+	return makePrivateDir(r.getBinaryDir(binaryName))
+}
+
 // getProfilePath returns the file path for a profile
 func (r *FilesystemRepository) getProfilePath(profileName, binaryName string) string {
 	return filepath.Join(r.getBinaryDir(binaryName), profileName+".env")
 }
 
+// findProfileFile returns the file of an existing profile and whether it is encrypted
+func (r *FilesystemRepository) findProfileFile(profileName, binaryName string) (string, bool, error) {
+	profilePath := r.getProfilePath(profileName, binaryName)
+	if _, err := os.Stat(profilePath); err == nil {
+		return profilePath, false, nil
+	}
+	if _, err := os.Stat(profilePath + encryptedSuffix); err == nil {
+		return profilePath + encryptedSuffix, true, nil
+	}
+	return "", false, domain.ErrProfileNotFound
+}
+
+// profileNameFromFile extracts the profile name from a profile file name
+func profileNameFromFile(fileName string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(fileName, encryptedSuffix), ".env")
+}
+
 // getCurrentSymlink returns the path to the current profile symlink
 func (r *FilesystemRepository) getCurrentSymlink(binaryName string) string {
 	return filepath.Join(r.getBinaryDir(binaryName), "current.env")
@@ -48,26 +100,39 @@ func (r *FilesystemRepository) getDefaultPath(binaryName string) string {
 
 // Save saves a profile to the filesystem
 func (r *FilesystemRepository) Save(profile *domain.Profile) error {
-	binaryDir := r.getBinaryDir(profile.BinaryName())
-
 	// Create directory if it doesn't exist
-	if err := os.MkdirAll(binaryDir, 0755); err != nil {
+	if err := r.makeBinaryDir(profile.BinaryName()); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	profilePath := r.getProfilePath(profile.Name(), profile.BinaryName())
-
-	// Write environment variables
-	file, err := os.Create(profilePath)
+	unlock, err := r.lock(profile.BinaryName())
 	if err != nil {
-		return fmt.Errorf("failed to create profile file: %w", err)
+		return err
 	}
-	defer file.Close()
+	defer unlock()
 
-	for key, value := range profile.Environment() {
-		if _, err := fmt.Fprintf(file, "%s=%s\n", key, value); err != nil {
-			return fmt.Errorf("failed to write environment variable: %w", err)
+	// Load the existing document so comments and ordering are preserved
+	doc := &envDocument{}
+	profilePath, encrypted, err := r.findProfileFile(profile.Name(), profile.BinaryName())
+	if err == nil {
+		doc, err = r.readEnvFile(profilePath, encrypted)
+		if err != nil {
+			return fmt.Errorf("failed to read existing profile: %w", err)
 		}
+	} else {
+		profilePath = r.getProfilePath(profile.Name(), profile.BinaryName())
+	}
+
+	doc.apply(profile)
+
+	data, err := r.encode([]byte(doc.String()), encrypted)
+	if err != nil {
+		return err
+	}
+
+	// Write environment variables
+	if err := writeFileAtomic(profilePath, data, privateFileMode); err != nil {
+		return fmt.Errorf("failed to write profile file: %w", err)
 	}
 
 	return nil
@@ -75,20 +140,112 @@ func (r *FilesystemRepository) Save(profile *domain.Profile) error {
 
 // FindByName finds a profile by name
 func (r *FilesystemRepository) FindByName(profileName, binaryName string) (*domain.Profile, error) {
-	profilePath := r.getProfilePath(profileName, binaryName)
+	profilePath, encrypted, err := r.findProfileFile(profileName, binaryName)
+	if err != nil {
+		return nil, err
+	}
 
-	// Check if file exists
-	if _, err := os.Stat(profilePath); os.IsNotExist(err) {
-		return nil, domain.ErrProfileNotFound
+	if err := r.checkProfilePermissions(profilePath); err != nil {
+		return nil, err
 	}
 
 	// Read environment variables
-	env, err := r.readEnvFile(profilePath)
+	doc, err := r.readEnvFile(profilePath, encrypted)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read profile: %w", err)
 	}
 
-	return domain.NewProfile(profileName, binaryName, env)
+	profile, err := doc.profile(profileName, binaryName)
+	if err != nil {
+		return nil, err
+	}
+	profile.SetEncrypted(encrypted)
+
+	return profile, nil
+}
+
+// Exists reports whether a profile file exists, even one that can't be read
+func (r *FilesystemRepository) Exists(profileName, binaryName string) bool {
+	_, _, err := r.findProfileFile(profileName, binaryName)
+	return err == nil
+}
+
+// Resolve finds a profile by name and merges it with the profiles it extends
+func (r *FilesystemRepository) Resolve(profileName, binaryName string) (*domain.Profile, error) {
+	profile, err := r.FindByName(profileName, binaryName)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.ResolveInheritance(profile, func(parentName string) (*domain.Profile, error) {
+		return r.FindByName(parentName, binaryName)
+	})
+}
+
+// ReadContent reads the raw content of a profile file
+func (r *FilesystemRepository) ReadContent(profileName, binaryName string) ([]byte, error) {
+	profilePath, encrypted, err := r.findProfileFile(profileName, binaryName)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := r.readProfileFile(profilePath, encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile: %w", err)
+	}
+
+	return data, nil
+}
+
+// WriteContent validates raw profile content and atomically replaces the profile file
+func (r *FilesystemRepository) WriteContent(profileName, binaryName string, content []byte) error {
+	doc, err := parseDotenv(string(content))
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidProfile, err)
+	}
+
+	profile, err := doc.profile(profileName, binaryName)
+	if err != nil {
+		return err
+	}
+
+	// Check the inheritance chain using the new content for this profile
+	_, err = domain.ResolveInheritance(profile, func(parentName string) (*domain.Profile, error) {
+		if parentName == profileName {
+			return profile, nil
+		}
+		return r.FindByName(parentName, binaryName)
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidProfile, err)
+	}
+
+	if err := r.makeBinaryDir(binaryName); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	unlock, err := r.lock(binaryName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Keep the encryption of the existing file
+	profilePath, encrypted, err := r.findProfileFile(profileName, binaryName)
+	if err != nil {
+		profilePath = r.getProfilePath(profileName, binaryName)
+	}
+
+	data, err := r.encode(content, encrypted)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(profilePath, data, privateFileMode); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+
+	return nil
 }
 
 // List lists all profiles for a binary
@@ -107,8 +264,10 @@ func (r *FilesystemRepository) List(binaryName string) ([]*domain.Profile, error
 	}
 
 	var profiles []*domain.Profile
+	seen := make(map[string]bool)
 	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".env") {
+		encrypted := strings.HasSuffix(entry.Name(), ".env"+encryptedSuffix)
+		if entry.IsDir() || !(strings.HasSuffix(entry.Name(), ".env") || encrypted) {
 			continue
 		}
 
@@ -118,10 +277,28 @@ func (r *FilesystemRepository) List(binaryName string) ([]*domain.Profile, error
 		}
 
 		// Extract profile name
-		profileName := strings.TrimSuffix(entry.Name(), ".env")
+		profileName := profileNameFromFile(entry.Name())
+		if seen[profileName] {
+			continue
+		}
+		seen[profileName] = true
 
 		// Load profile
 		profile, err := r.FindByName(profileName, binaryName)
+		if errors.Is(err, domain.ErrInsecurePermissions) {
+			// Refused profiles are listed so that they don't silently disappear
+			profile, err = domain.NewProfile(profileName, binaryName, nil)
+			if err == nil {
+				profile.SetEncrypted(encrypted)
+				profile.SetInsecure(true)
+			}
+		} else if err != nil && encrypted {
+			// Still list encrypted profiles that can't be decrypted right now
+			profile, err = domain.NewProfile(profileName, binaryName, nil)
+			if err == nil {
+				profile.SetEncrypted(true)
+			}
+		}
 		if err != nil {
 			continue // Skip invalid profiles
 		}
@@ -134,11 +311,15 @@ func (r *FilesystemRepository) List(binaryName string) ([]*domain.Profile, error
 
 // Delete deletes a profile
 func (r *FilesystemRepository) Delete(profileName, binaryName string) error {
-	profilePath := r.getProfilePath(profileName, binaryName)
+	unlock, err := r.lock(binaryName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	// Check if file exists
-	if _, err := os.Stat(profilePath); os.IsNotExist(err) {
-		return domain.ErrProfileNotFound
+	profilePath, _, err := r.findProfileFile(profileName, binaryName)
+	if err != nil {
+		return err
 	}
 
 	// Delete file
@@ -149,22 +330,121 @@ func (r *FilesystemRepository) Delete(profileName, binaryName string) error {
 	return nil
 }
 
+// Rename renames a profile, replacing an existing target when overwrite is set
+func (r *FilesystemRepository) Rename(profileName, newName, binaryName string, overwrite bool) error {
+	unlock, err := r.lock(binaryName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	sourcePath, encrypted, err := r.findProfileFile(profileName, binaryName)
+	if err != nil {
+		return err
+	}
+	existingPath, err := r.checkTarget(newName, binaryName, overwrite)
+	if err != nil {
+		return err
+	}
+
+	targetPath := r.getProfilePath(newName, binaryName)
+	if encrypted {
+		targetPath += encryptedSuffix
+	}
+
+	// Rename file, an existing target file is replaced in a single step
+	if err := os.Rename(sourcePath, targetPath); err != nil {
+		return fmt.Errorf("failed to rename profile: %w", err)
+	}
+
+	return r.removeReplaced(existingPath, targetPath)
+}
+
+// Copy copies a profile, replacing an existing target when overwrite is set
+func (r *FilesystemRepository) Copy(profileName, newName, binaryName string, overwrite bool) error {
+	unlock, err := r.lock(binaryName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	sourcePath, encrypted, err := r.findProfileFile(profileName, binaryName)
+	if err != nil {
+		return err
+	}
+	existingPath, err := r.checkTarget(newName, binaryName, overwrite)
+	if err != nil {
+		return err
+	}
+
+	// Encrypted content is copied as is, it doesn't depend on the file name
+	targetPath := r.getProfilePath(newName, binaryName)
+	if encrypted {
+		targetPath += encryptedSuffix
+	}
+
+	// Copy raw content to keep comments and formatting
+	data, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return fmt.Errorf("failed to read profile: %w", err)
+	}
+
+	if err := writeFileAtomic(targetPath, data, privateFileMode); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+
+	return r.removeReplaced(existingPath, targetPath)
+}
+
+// checkTarget returns the file of the profile replaced by a rename or copy, ""
+// if there is none. An existing profile is only replaced when overwrite is set.
+func (r *FilesystemRepository) checkTarget(newName, binaryName string, overwrite bool) (string, error) {
+	existingPath, _, err := r.findProfileFile(newName, binaryName)
+	if err != nil {
+		return "", nil
+	}
+	if !overwrite {
+		return "", domain.ErrProfileAlreadyExists
+	}
+	return existingPath, nil
+}
+
+// removeReplaced removes the file of a replaced profile when it was not
+// overwritten by the new one, i.e. only one of them is encrypted
+func (r *FilesystemRepository) removeReplaced(existingPath, targetPath string) error {
+	if existingPath == "" || existingPath == targetPath {
+		return nil
+	}
+
+	if err := os.Remove(existingPath); err != nil {
+		return fmt.Errorf("failed to remove replaced profile: %w", err)
+	}
+
+	return nil
+}
+
 // SetCurrent sets the current profile
 func (r *FilesystemRepository) SetCurrent(profileName, binaryName string) error {
-	profilePath := r.getProfilePath(profileName, binaryName)
-
-	// Check if profile exists
-	if _, err := os.Stat(profilePath); os.IsNotExist(err) {
-		return domain.ErrProfileNotFound
+	unlock, err := r.lock(binaryName)
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	symlinkPath := r.getCurrentSymlink(binaryName)
+	return r.setCurrent(profileName, binaryName)
+}
 
-	// Remove existing symlink if it exists
-	_ = os.Remove(symlinkPath)
+// setCurrent points the current profile symlink to a profile, the lock of the
+// binary directory must be held
+func (r *FilesystemRepository) setCurrent(profileName, binaryName string) error {
+	// Check if profile exists
+	profilePath, _, err := r.findProfileFile(profileName, binaryName)
+	if err != nil {
+		return err
+	}
 
-	// Create new symlink
-	if err := os.Symlink(profilePath, symlinkPath); err != nil {
+	// Replace the symlink in a single step, it never goes missing
+	if err := symlinkAtomic(profilePath, r.getCurrentSymlink(binaryName)); err != nil {
 		return fmt.Errorf("failed to create symlink: %w", err)
 	}
 
@@ -173,36 +453,68 @@ func (r *FilesystemRepository) SetCurrent(profileName, binaryName string) error
 
 // GetCurrent gets the current profile
 func (r *FilesystemRepository) GetCurrent(binaryName string) (*domain.Profile, error) {
+	profileName, err := r.CurrentName(binaryName)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindByName(profileName, binaryName)
+}
+
+// CurrentName returns the name the current profile symlink points to, without
+// reading the profile
+func (r *FilesystemRepository) CurrentName(binaryName string) (string, error) {
 	symlinkPath := r.getCurrentSymlink(binaryName)
 
 	// Check if symlink exists
 	if _, err := os.Lstat(symlinkPath); os.IsNotExist(err) {
-		return nil, domain.ErrNoCurrentProfile
+		return "", domain.ErrNoCurrentProfile
 	}
 
 	// Read target
 	target, err := os.Readlink(symlinkPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read symlink: %w", err)
+		return "", fmt.Errorf("failed to read symlink: %w", err)
 	}
 
 	// Extract profile name from target
-	profileName := strings.TrimSuffix(filepath.Base(target), ".env")
+	return profileNameFromFile(filepath.Base(target)), nil
+}
 
-	return r.FindByName(profileName, binaryName)
+// UnsetCurrent removes the current profile symlink
+func (r *FilesystemRepository) UnsetCurrent(binaryName string) error {
+	unlock, err := r.lock(binaryName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	symlinkPath := r.getCurrentSymlink(binaryName)
+
+	if err := os.Remove(symlinkPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove symlink: %w", err)
+	}
+
+	return nil
 }
 
 // SetDefault sets the default profile
 func (r *FilesystemRepository) SetDefault(profileName, binaryName string) error {
-	// Check if profile exists
-	if _, err := r.FindByName(profileName, binaryName); err != nil {
+	unlock, err := r.lock(binaryName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Check if profile exists, a profile that can't be read is still a valid target
+	if _, _, err := r.findProfileFile(profileName, binaryName); err != nil {
 		return err
 	}
 
 	defaultPath := r.getDefaultPath(binaryName)
 
 	// Write default profile name
-	if err := os.WriteFile(defaultPath, []byte(profileName), 0644); err != nil {
+	if err := writeFileAtomic(defaultPath, []byte(profileName), privateFileMode); err != nil {
 		return fmt.Errorf("failed to write default profile: %w", err)
 	}
 
@@ -211,74 +523,218 @@ func (r *FilesystemRepository) SetDefault(profileName, binaryName string) error
 
 // GetDefault gets the default profile
 func (r *FilesystemRepository) GetDefault(binaryName string) (*domain.Profile, error) {
+	profileName, err := r.DefaultName(binaryName)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.FindByName(profileName, binaryName)
+}
+
+// DefaultName returns the name stored in the default profile marker, without
+// reading the profile
+func (r *FilesystemRepository) DefaultName(binaryName string) (string, error) {
 	defaultPath := r.getDefaultPath(binaryName)
 
 	// Check if file exists
 	if _, err := os.Stat(defaultPath); os.IsNotExist(err) {
-		return nil, domain.ErrNoDefaultProfile
+		return "", domain.ErrNoDefaultProfile
 	}
 
 	// Read default profile name
 	data, err := os.ReadFile(defaultPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read default profile: %w", err)
+		return "", fmt.Errorf("failed to read default profile: %w", err)
 	}
 
-	profileName := strings.TrimSpace(string(data))
-	return r.FindByName(profileName, binaryName)
+	return strings.TrimSpace(string(data)), nil
+}
+
+// UnsetDefault removes the default profile marker
+func (r *FilesystemRepository) UnsetDefault(binaryName string) error {
+	unlock, err := r.lock(binaryName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	defaultPath := r.getDefaultPath(binaryName)
+
+	if err := os.Remove(defaultPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove default profile: %w", err)
+	}
+
+	return nil
 }
 
-// GetActiveProfile gets the active profile (current if set, otherwise default)
+// GetActiveProfile gets the active profile (current if set, otherwise default),
+// merged with the profiles it extends
 func (r *FilesystemRepository) GetActiveProfile(binaryName string) (*domain.Profile, error) {
-	// Try current first
+	// Try current first. Only a missing profile falls back to the default
+	// one: a profile that can't be read is reported rather than replaced.
 	profile, err := r.GetCurrent(binaryName)
-	if err == nil {
-		return profile, nil
+	if err != nil {
+		if err != domain.ErrNoCurrentProfile && err != domain.ErrProfileNotFound {
+			return nil, err
+		}
+
+		// Fall back to default
+		profile, err = r.GetDefault(binaryName)
+		if err == domain.ErrNoDefaultProfile || err == domain.ErrProfileNotFound {
+			return nil, domain.ErrNoCurrentProfile
+		}
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Fall back to default
-	profile, err = r.GetDefault(binaryName)
-	if err == nil {
-		return profile, nil
+	return r.Resolve(profile.Name(), binaryName)
+}
+
+// Encrypt replaces a plain text profile file with an encrypted one
+func (r *FilesystemRepository) Encrypt(profileName, binaryName string) error {
+	unlock, err := r.lock(binaryName)
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	return nil, domain.ErrNoCurrentProfile
+	profilePath, encrypted, err := r.findProfileFile(profileName, binaryName)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		return domain.ErrProfileEncrypted
+	}
+
+	return r.convert(profileName, binaryName, profilePath, profilePath+encryptedSuffix, false)
 }
 
-// readEnvFile reads environment variables from a .env file
-func (r *FilesystemRepository) readEnvFile(path string) (map[string]string, error) {
-	file, err := os.Open(path)
+// Decrypt replaces an encrypted profile file with a plain text one
+func (r *FilesystemRepository) Decrypt(profileName, binaryName string) error {
+	unlock, err := r.lock(binaryName)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer file.Close()
+	defer unlock()
 
-	env := make(map[string]string)
-	scanner := bufio.NewScanner(file)
+	profilePath, encrypted, err := r.findProfileFile(profileName, binaryName)
+	if err != nil {
+		return err
+	}
+	if !encrypted {
+		return domain.ErrProfileNotEncrypted
+	}
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+	return r.convert(profileName, binaryName, profilePath, strings.TrimSuffix(profilePath, encryptedSuffix), true)
+}
 
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
+// convert rewrites a profile file encrypted or decrypted under its new path,
+// then removes the old file and moves the current profile symlink
+func (r *FilesystemRepository) convert(profileName, binaryName, sourcePath, targetPath string, encrypted bool) error {
+	data, err := r.readProfileFile(sourcePath, encrypted)
+	if err != nil {
+		return fmt.Errorf("failed to read profile: %w", err)
+	}
 
-		// Parse key=value
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
-			continue
+	data, err = r.encode(data, !encrypted)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(targetPath, data, privateFileMode); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+
+	if err := os.Remove(sourcePath); err != nil {
+		return fmt.Errorf("failed to remove profile: %w", err)
+	}
+
+	target, err := os.Readlink(r.getCurrentSymlink(binaryName))
+	if err == nil && profileNameFromFile(filepath.Base(target)) == profileName {
+		return r.setCurrent(profileName, binaryName)
+	}
+
+	return nil
+}
+
+// Lock takes the lock of the profiles of a binary, see lock
+func (r *FilesystemRepository) Lock(binaryName string) (func(), error) {
+	return r.lock(binaryName)
+}
+
+// lock takes the advisory lock of a binary directory, serializing the updates
+// of its profiles and markers across processes. The lock is re-entrant within
+// the process, so methods can take it while a caller holds it. The returned
+// function releases it.
+func (r *FilesystemRepository) lock(binaryName string) (func(), error) {
+	release := func() {
+		held := r.locks[binaryName]
+		held.depth--
+		if held.depth == 0 {
+			delete(r.locks, binaryName)
+			_ = unlockFile(held.file)
+			held.file.Close()
 		}
+	}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
+	if held, ok := r.locks[binaryName]; ok {
+		held.depth++
+		return release, nil
+	}
+
+	lockPath := filepath.Join(r.getBinaryDir(binaryName), lockFileName)
 
-		env[key] = value
+	file, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, privateFileMode)
+	if os.IsNotExist(err) {
+		// No directory yet, so there is nothing to update concurrently
+		return func() {}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
 	}
 
-	if err := scanner.Err(); err != nil {
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock profiles: %w", err)
+	}
+
+	r.locks[binaryName] = &heldLock{file: file, depth: 1}
+	return release, nil
+}
+
+// readProfileFile reads the content of a profile file, decrypting it if needed
+func (r *FilesystemRepository) readProfileFile(path string, encrypted bool) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil || !encrypted {
+		return data, err
+	}
+
+	if r.cipher == nil {
+		return nil, domain.ErrEncryptionKeyUnavailable
+	}
+	return r.cipher.Decrypt(data)
+}
+
+// encode returns the content to write in a profile file, encrypting it if needed
+func (r *FilesystemRepository) encode(data []byte, encrypted bool) ([]byte, error) {
+	if !encrypted {
+		return data, nil
+	}
+
+	if r.cipher == nil {
+		return nil, domain.ErrEncryptionKeyUnavailable
+	}
+
+	return r.cipher.Encrypt(data)
+}
+
+// readEnvFile reads a .env file into a document
+func (r *FilesystemRepository) readEnvFile(path string, encrypted bool) (*envDocument, error) {
+	data, err := r.readProfileFile(path, encrypted)
+	if err != nil {
 		return nil, err
 	}
 
-	return env, nil
+	return parseDotenv(string(data))
 }
@@ -0,0 +1,182 @@
+package infrastructure
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jycamier/wrapper/internal/domain"
+)
+
+// Layout of encrypted files: a header line followed by the base64 encoding of
+// salt, nonce and AES-256-GCM ciphertext
+const (
+	encryptedHeader  = "wrapper-encrypted:v1"
+	saltSize         = 16
+	keySize          = 32
+	kdfIterations    = 600000
+	base64LineLength = 64
+)
+
+// PassphraseCipher encrypts profiles with a key derived from a passphrase
+// (PBKDF2-SHA256, per-file salt). The passphrase is read from
+// WRAPPER_PASSPHRASE, the output of WRAPPER_PASSPHRASE_COMMAND or the key
+// file, in that order, the first time it is needed. Like profiles, the key
+// file is checked according to WRAPPER_PERMISSION_CHECK.
+type PassphraseCipher struct {
+	keyFile    string
+	passphrase []byte
+	keys       map[string][]byte
+	warned     map[string]bool
+}
+
+// NewPassphraseCipher creates a new PassphraseCipher
+func NewPassphraseCipher() (*PassphraseCipher, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	keyFile := filepath.Join(homeDir, ".config", "wrapper", "key")
+	return &PassphraseCipher{keyFile: keyFile, keys: make(map[string][]byte), warned: make(map[string]bool)}, nil
+}
+
+// Encrypt encrypts the content of a profile file
+func (c *PassphraseCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	aead, err := c.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := append(append(salt, nonce...), aead.Seal(nil, nonce, plaintext, []byte(encryptedHeader))...)
+	encoded := base64.StdEncoding.EncodeToString(sealed)
+
+	var b strings.Builder
+	b.WriteString(encryptedHeader + "\n")
+	for len(encoded) > base64LineLength {
+		b.WriteString(encoded[:base64LineLength] + "\n")
+		encoded = encoded[base64LineLength:]
+	}
+	b.WriteString(encoded + "\n")
+
+	return []byte(b.String()), nil
+}
+
+// Decrypt decrypts the content of an encrypted profile file
+func (c *PassphraseCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	header, body, _ := strings.Cut(string(ciphertext), "\n")
+	if strings.TrimSpace(header) != encryptedHeader {
+		return nil, fmt.Errorf("%w: unknown format", domain.ErrDecryptionFailed)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(body), ""))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrDecryptionFailed, err)
+	}
+	if len(sealed) < saltSize {
+		return nil, domain.ErrDecryptionFailed
+	}
+
+	aead, err := c.aead(sealed[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+
+	sealed = sealed[saltSize:]
+	if len(sealed) < aead.NonceSize() {
+		return nil, domain.ErrDecryptionFailed
+	}
+
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, []byte(encryptedHeader))
+	if err != nil {
+		return nil, domain.ErrDecryptionFailed
+	}
+
+	return plaintext, nil
+}
+
+// aead returns the AES-GCM cipher keyed for a salt. Derived keys are cached:
+// the same file is often read several times in a single invocation.
+func (c *PassphraseCipher) aead(salt []byte) (cipher.AEAD, error) {
+	key, ok := c.keys[string(salt)]
+	if !ok {
+		passphrase, err := c.readPassphrase()
+		if err != nil {
+			return nil, err
+		}
+
+		key, err = pbkdf2.Key(sha256.New, string(passphrase), salt, kdfIterations, keySize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key: %w", err)
+		}
+		c.keys[string(salt)] = key
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// readPassphrase returns the passphrase, reading it on first use
+func (c *PassphraseCipher) readPassphrase() ([]byte, error) {
+	if c.passphrase != nil {
+		return c.passphrase, nil
+	}
+
+	var passphrase []byte
+	if value := os.Getenv(domain.PassphraseEnvVar); value != "" {
+		passphrase = []byte(value)
+	} else if command := os.Getenv(domain.PassphraseCommandEnvVar); command != "" {
+		output, err := shellCommand(command).Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to run %s: %w", domain.PassphraseCommandEnvVar, err)
+		}
+		passphrase = output
+	} else {
+		data, err := os.ReadFile(c.keyFile)
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: set %s, %s or create %s",
+				domain.ErrEncryptionKeyUnavailable, domain.PassphraseEnvVar, domain.PassphraseCommandEnvVar, c.keyFile)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		if check := permissionCheck(); check != PermissionCheckOff {
+			if err := checkPermissions(check, c.keyFile, privateFileMode, c.warned); err != nil {
+				return nil, err
+			}
+		}
+		passphrase = data
+	}
+
+	// Commands and files usually end with a newline
+	passphrase = bytes.TrimRight(passphrase, "\r\n")
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("%w: empty passphrase", domain.ErrEncryptionKeyUnavailable)
+	}
+
+	c.passphrase = passphrase
+	return passphrase, nil
+}
@@ -0,0 +1,233 @@
+package infrastructure
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jycamier/wrapper/internal/domain"
+)
+
+// projectConfigFiles are the file names looked up in each directory, in order
+var projectConfigFiles = []string{".wrapper", ".wrapper.toml"}
+
+// FilesystemProjectConfigFinder finds .wrapper files and keeps track of the
+// ones the user trusts. A file is trusted for a given content only: editing it
+// requires allowing it again.
+type FilesystemProjectConfigFinder struct {
+	trustFile string
+}
+
+// NewFilesystemProjectConfigFinder creates a new FilesystemProjectConfigFinder
+func NewFilesystemProjectConfigFinder() (*FilesystemProjectConfigFinder, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	trustFile := filepath.Join(homeDir, ".config", "wrapper", "trusted")
+	return &FilesystemProjectConfigFinder{trustFile: trustFile}, nil
+}
+
+// Find returns the configuration nearest to dir, walking up to the root
+func (f *FilesystemProjectConfigFinder) Find(dir string) (*domain.ProjectConfig, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve directory: %w", err)
+	}
+
+	for {
+		for _, name := range projectConfigFiles {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				return f.load(path)
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, domain.ErrProjectConfigNotFound
+		}
+		dir = parent
+	}
+}
+
+// Load reads the configuration file at path
+func (f *FilesystemProjectConfigFinder) Load(path string) (*domain.ProjectConfig, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	return f.load(path)
+}
+
+// Allow trusts a configuration file in its current content
+func (f *FilesystemProjectConfigFinder) Allow(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read project configuration: %w", err)
+	}
+
+	// Refuse to trust a file that doesn't parse
+	if _, err := parseProjectConfig(string(data)); err != nil {
+		return fmt.Errorf("invalid project configuration %s: %w", path, err)
+	}
+
+	trusted, err := f.readTrusted()
+	if err != nil {
+		return err
+	}
+	trusted[path] = contentHash(data)
+
+	return f.writeTrusted(trusted)
+}
+
+// Deny revokes the trust of a configuration file
+func (f *FilesystemProjectConfigFinder) Deny(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	trusted, err := f.readTrusted()
+	if err != nil {
+		return err
+	}
+	delete(trusted, path)
+
+	return f.writeTrusted(trusted)
+}
+
+// load reads a configuration file and checks whether it is trusted
+func (f *FilesystemProjectConfigFinder) load(path string) (*domain.ProjectConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project configuration: %w", err)
+	}
+
+	profiles, err := parseProjectConfig(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid project configuration %s: %w", path, err)
+	}
+
+	trusted, err := f.readTrusted()
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.NewProjectConfig(path, profiles, trusted[path] == contentHash(data)), nil
+}
+
+// readTrusted reads the trusted files as a path to content hash mapping
+func (f *FilesystemProjectConfigFinder) readTrusted() (map[string]string, error) {
+	trusted := make(map[string]string)
+
+	file, err := os.Open(f.trustFile)
+	if os.IsNotExist(err) {
+		return trusted, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trusted files: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Same layout as sha256sum: "<hash>  <path>"
+		hash, path, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok {
+			continue
+		}
+		trusted[path] = hash
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read trusted files: %w", err)
+	}
+
+	return trusted, nil
+}
+
+// writeTrusted writes the trusted files
+func (f *FilesystemProjectConfigFinder) writeTrusted(trusted map[string]string) error {
+	if err := makePrivateDir(filepath.Dir(f.trustFile)); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	paths := make([]string, 0, len(trusted))
+	for path := range trusted {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	for _, path := range paths {
+		fmt.Fprintf(&b, "%s  %s\n", trusted[path], path)
+	}
+
+	if err := writeFileAtomic(f.trustFile, []byte(b.String()), privateFileMode); err != nil {
+		return fmt.Errorf("failed to write trusted files: %w", err)
+	}
+
+	return nil
+}
+
+// parseProjectConfig parses "binary = profile" lines. Comments, quoted values
+// and a [profiles] table header are accepted so the file can be valid TOML.
+func parseProjectConfig(input string) (map[string]string, error) {
+	profiles := make(map[string]string)
+
+	for i, line := range strings.Split(input, "\n") {
+		line = strings.TrimSpace(line)
+
+		// Skip empty lines, comments and the [profiles] table header
+		if line == "" || strings.HasPrefix(line, "#") || line == "[profiles]" {
+			continue
+		}
+
+		binary, profile, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected 'binary = profile'", i+1)
+		}
+
+		binary = unquote(strings.TrimSpace(binary))
+		profile = strings.TrimSpace(profile)
+		if idx := strings.Index(profile, " #"); idx >= 0 {
+			profile = strings.TrimSpace(profile[:idx])
+		}
+		profile = unquote(profile)
+
+		if binary == "" || profile == "" {
+			return nil, fmt.Errorf("line %d: expected 'binary = profile'", i+1)
+		}
+
+		profiles[binary] = profile
+	}
+
+	return profiles, nil
+}
+
+// unquote removes matching single or double quotes around a value
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// contentHash returns the hex encoded SHA-256 of data
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
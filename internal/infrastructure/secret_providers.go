@@ -0,0 +1,79 @@
+package infrastructure
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jycamier/wrapper/internal/domain"
+)
+
+// FileSecretProvider reads secrets from files, e.g. ref+file:///run/secrets/vault.
+// A leading ~/ is replaced by the home directory.
+type FileSecretProvider struct{}
+
+// Fetch returns the content of a file without its trailing newline
+func (p *FileSecretProvider) Fetch(location string) (string, error) {
+	if strings.HasPrefix(location, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		location = filepath.Join(homeDir, location[2:])
+	}
+
+	data, err := os.ReadFile(location)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("%w: %s does not exist", domain.ErrSecretNotFound, location)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return trimNewline(string(data)), nil
+}
+
+// CommandSecretProvider runs a command printing the secret, e.g.
+// ref+cmd://pass show corp/vault
+type CommandSecretProvider struct{}
+
+// Fetch returns the output of a command without its trailing newline
+func (p *CommandSecretProvider) Fetch(location string) (string, error) {
+	output, err := shellCommand(location).Output()
+	if err != nil {
+		return "", err
+	}
+
+	return trimNewline(string(output)), nil
+}
+
+// EnvSecretProvider reads secrets from variables of the calling environment,
+// e.g. ref+env://CI_VAULT_TOKEN
+type EnvSecretProvider struct{}
+
+// Fetch returns the value of an environment variable
+func (p *EnvSecretProvider) Fetch(location string) (string, error) {
+	value, ok := os.LookupEnv(location)
+	if !ok {
+		return "", fmt.Errorf("%w: %s is not set", domain.ErrSecretNotFound, location)
+	}
+
+	return value, nil
+}
+
+// NewSecretResolver creates a resolver handling the file, cmd and env schemes.
+// The cache may be nil.
+func NewSecretResolver(cache domain.SecretCache) *domain.SecretResolver {
+	resolver := domain.NewSecretResolver(cache)
+	resolver.Register("file", &FileSecretProvider{})
+	resolver.Register("cmd", &CommandSecretProvider{})
+	resolver.Register("env", &EnvSecretProvider{})
+	return resolver
+}
+
+// trimNewline removes a single trailing newline, as printed by most commands
+func trimNewline(value string) string {
+	value = strings.TrimSuffix(value, "\n")
+	return strings.TrimSuffix(value, "\r")
+}
@@ -0,0 +1,37 @@
+package infrastructure
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/jycamier/wrapper/internal/domain"
+)
+
+// shellCommand prepares a command line run by the shell of the platform. The
+// command can prompt on the terminal: only its standard output is captured.
+func shellCommand(command string) *exec.Cmd {
+	cmd := exec.Command("sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+	cmd.Env = withoutPassphrase(os.Environ())
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// withoutPassphrase removes the passphrase of encrypted profiles from an
+// environment, so that commands never see it
+func withoutPassphrase(environ []string) []string {
+	filtered := make([]string, 0, len(environ))
+	for _, entry := range environ {
+		key, _, _ := strings.Cut(entry, "=")
+		if key == domain.PassphraseEnvVar || key == domain.PassphraseCommandEnvVar {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}